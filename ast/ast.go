@@ -46,18 +46,32 @@ func (p *Program) String() string {
 }
 
 type LetStatement struct {
-	Token token.Token // token.LET token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // token.LET or token.CONST token
+	Name    *Identifier
+	Pattern Expression // *ArrayPattern or *HashPattern when destructuring, Name is nil then
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+
+// IsConst reports whether the binding was declared with `const`.
+func (ls *LetStatement) IsConst() bool { return ls.Token.Type == token.CONST }
+
+// Target returns what the value is bound to: the destructuring pattern if
+// there is one, the plain name otherwise.
+func (ls *LetStatement) Target() Expression {
+	if ls.Pattern != nil {
+		return ls.Pattern
+	}
+	return ls.Name
+}
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	out.WriteString(ls.Target().String())
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -172,11 +186,20 @@ func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+type NullLiteral struct {
+	Token token.Token // the 'null' token
+}
+
+func (n *NullLiteral) expressionNode()      {}
+func (n *NullLiteral) TokenLiteral() string { return n.Token.Literal }
+func (n *NullLiteral) String() string       { return "null" }
+
 type IfExpression struct {
 	Token       token.Token // 'if' token
 	Condition   Expression
 	Consequence *BlockStatement
 	Alternative *BlockStatement
+	ElseIf      *IfExpression // the if of `else if (...)`, Alternative is nil then
 }
 
 func (ie *IfExpression) expressionNode()      {}
@@ -184,21 +207,56 @@ func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("if")
-	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
+	out.WriteString("if ")
+	switch ie.Condition.(type) {
+	case *InfixExpression, *PrefixExpression, *ConditionalExpression:
+		// already in parentheses
+		out.WriteString(ie.Condition.String())
+	default:
+		out.WriteString("(" + ie.Condition.String() + ")")
+	}
+	out.WriteString(" { ")
 	out.WriteString(ie.Consequence.String())
-	if ie.Alternative != nil {
-		out.WriteString("else")
+	out.WriteString(" }")
+	if ie.ElseIf != nil {
+		out.WriteString(" else ")
+		out.WriteString(ie.ElseIf.String())
+	} else if ie.Alternative != nil {
+		out.WriteString(" else { ")
 		out.WriteString(ie.Alternative.String())
+		out.WriteString(" }")
 	}
 
 	return out.String()
 }
 
+type ConditionalExpression struct {
+	Token       token.Token // the '?' token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Rbrace     token.Token // the closing '}' token
 }
 
 func (bs *BlockStatement) statementNode()       {}
@@ -214,8 +272,8 @@ func (bs *BlockStatement) String() string {
 }
 
 type FunctionLiteral struct {
-	Token      token.Token // 'fn' token
-	Parameters []*Identifier
+	Token      token.Token  // 'fn' token
+	Parameters []Expression // *Identifier, *ArrayPattern or *HashPattern
 	Body       *BlockStatement
 }
 
@@ -238,6 +296,31 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+type MacroLiteral struct {
+	Token      token.Token // 'macro' token
+	Parameters []Expression
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	var params []string
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
 type CallExpression struct {
 	Token     token.Token // '(' token
 	Function  Expression  // Identifier or FunctionLiteral
@@ -334,3 +417,123 @@ func (hl *HashMapLiteral) String() string {
 
 	return out.String()
 }
+
+type MatchExpression struct {
+	Token   token.Token // 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+	Rbrace  token.Token // the closing '}' token
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	var arms []string
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+type MatchArm struct {
+	Token   token.Token // the '=>' token
+	Pattern Expression
+	Guard   Expression // condition after `if`, may be nil
+	Body    Expression
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// Patterns
+//
+// Besides the nodes below, identifiers bind the matched value (`_` matches
+// anything without binding) and integer, string and boolean literals match
+// equal values.
+type ArrayPattern struct {
+	Token    token.Token  // the '[' token
+	Elements []Expression // sub-patterns matched against each element
+	Rest     *Identifier  // binds the remaining elements after `...`, may be nil
+}
+
+func (ap *ArrayPattern) expressionNode()      {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	var elements []string
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+type HashPatternPair struct {
+	Key   Expression // literal key looked up in the hashmap
+	Value Expression // sub-pattern matched against the value under Key
+}
+
+type HashPattern struct {
+	Token token.Token // the '{' token
+	Pairs []HashPatternPair
+}
+
+func (hp *HashPattern) expressionNode()      {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	var pairs []string
+	for _, pair := range hp.Pairs {
+		if hp.isShorthand(pair) {
+			pairs = append(pairs, pair.Value.String())
+		} else {
+			pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+		}
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// isShorthand reports whether pair was written as `{name}`, binding the value
+// under the key "name" to an identifier of the same name.
+func (hp *HashPattern) isShorthand(pair HashPatternPair) bool {
+	key, ok := pair.Key.(*StringLiteral)
+	if !ok || key.Token.Type != token.IDENT {
+		return false
+	}
+	ident, ok := pair.Value.(*Identifier)
+	return ok && ident.Value == key.Value
+}
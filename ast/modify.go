@@ -0,0 +1,148 @@
+package ast
+
+type ModifierFunc func(Node) Node
+
+// Modify walks the tree rooted at node depth-first, children before parents,
+// and replaces every node with the result of calling modifier on it.
+//
+// The tree passed in is left untouched: composite nodes on the way are
+// copied before their children are replaced, so the same AST can be
+// modified repeatedly, e.g. a quote inside a function that is called more
+// than once. Patterns are binding targets rather than expressions and are
+// not visited.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *ExpressionStatement:
+		n := *node
+		n.Expression, _ = Modify(node.Expression, modifier).(Expression)
+		return modifier(&n)
+
+	case *InfixExpression:
+		n := *node
+		n.Left, _ = Modify(node.Left, modifier).(Expression)
+		n.Right, _ = Modify(node.Right, modifier).(Expression)
+		return modifier(&n)
+
+	case *PrefixExpression:
+		n := *node
+		n.Right, _ = Modify(node.Right, modifier).(Expression)
+		return modifier(&n)
+
+	case *IndexExpression:
+		n := *node
+		n.Left, _ = Modify(node.Left, modifier).(Expression)
+		n.Index, _ = Modify(node.Index, modifier).(Expression)
+		return modifier(&n)
+
+	case *IfExpression:
+		n := *node
+		n.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		n.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+		if node.Alternative != nil {
+			n.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
+		}
+		if node.ElseIf != nil {
+			n.ElseIf, _ = Modify(node.ElseIf, modifier).(*IfExpression)
+		}
+		return modifier(&n)
+
+	case *ConditionalExpression:
+		n := *node
+		n.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		n.Consequence, _ = Modify(node.Consequence, modifier).(Expression)
+		n.Alternative, _ = Modify(node.Alternative, modifier).(Expression)
+		return modifier(&n)
+
+	case *MatchExpression:
+		n := *node
+		n.Subject, _ = Modify(node.Subject, modifier).(Expression)
+		n.Arms = make([]*MatchArm, len(node.Arms))
+		for i, arm := range node.Arms {
+			a := *arm
+			if arm.Guard != nil {
+				a.Guard, _ = Modify(arm.Guard, modifier).(Expression)
+			}
+			a.Body, _ = Modify(arm.Body, modifier).(Expression)
+			n.Arms[i] = &a
+		}
+		return modifier(&n)
+
+	case *BlockStatement:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *ReturnStatement:
+		n := *node
+		n.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
+		return modifier(&n)
+
+	case *LetStatement:
+		n := *node
+		n.Value, _ = Modify(node.Value, modifier).(Expression)
+		return modifier(&n)
+
+	case *FunctionLiteral:
+		n := *node
+		n.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+		return modifier(&n)
+
+	case *MacroLiteral:
+		n := *node
+		n.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+		return modifier(&n)
+
+	case *CallExpression:
+		n := *node
+		n.Function, _ = Modify(node.Function, modifier).(Expression)
+		n.Arguments = modifyExpressions(node.Arguments, modifier)
+		return modifier(&n)
+
+	case *ArrayLiteral:
+		n := *node
+		n.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&n)
+
+	case *HashMapLiteral:
+		n := *node
+		n.Pairs = make(map[Expression]Expression, len(node.Pairs))
+		for key, val := range node.Pairs {
+			newKey, _ := Modify(key, modifier).(Expression)
+			newVal, _ := Modify(val, modifier).(Expression)
+			n.Pairs[newKey] = newVal
+		}
+		return modifier(&n)
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(statements []Statement, modifier ModifierFunc) []Statement {
+	if statements == nil {
+		return nil
+	}
+
+	modified := make([]Statement, len(statements))
+	for i, statement := range statements {
+		modified[i], _ = Modify(statement, modifier).(Statement)
+	}
+	return modified
+}
+
+func modifyExpressions(expressions []Expression, modifier ModifierFunc) []Expression {
+	if expressions == nil {
+		return nil
+	}
+
+	modified := make([]Expression, len(expressions))
+	for i, expression := range expressions {
+		modified[i], _ = Modify(expression, modifier).(Expression)
+	}
+	return modified
+}
@@ -0,0 +1,55 @@
+package ast
+
+import "monkey-go/token"
+
+// Start returns the first token of node, which gives its position in the
+// source. Nodes built outside the parser may have no position.
+func Start(node Node) token.Token {
+	switch node := node.(type) {
+	case *LetStatement:
+		return node.Token
+	case *ReturnStatement:
+		return node.Token
+	case *ExpressionStatement:
+		return Start(node.Expression)
+	case *BlockStatement:
+		return node.Token
+	case *InfixExpression:
+		return Start(node.Left)
+	case *ConditionalExpression:
+		return Start(node.Condition)
+	case *CallExpression:
+		return Start(node.Function)
+	case *IndexExpression:
+		return Start(node.Left)
+	case *Identifier:
+		return node.Token
+	case *IntegerLiteral:
+		return node.Token
+	case *StringLiteral:
+		return node.Token
+	case *Boolean:
+		return node.Token
+	case *NullLiteral:
+		return node.Token
+	case *PrefixExpression:
+		return node.Token
+	case *IfExpression:
+		return node.Token
+	case *MatchExpression:
+		return node.Token
+	case *FunctionLiteral:
+		return node.Token
+	case *MacroLiteral:
+		return node.Token
+	case *ArrayLiteral:
+		return node.Token
+	case *HashMapLiteral:
+		return node.Token
+	case *ArrayPattern:
+		return node.Token
+	case *HashPattern:
+		return node.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,229 @@
+// Package cover measures which statements, branches and functions of a
+// Monkey program run. A Collector follows the program through the
+// evaluator hook; the Profile it produces can be written as an LCOV
+// tracefile or as an HTML page. It is used by `monkey run -cover`.
+package cover
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/evaluator"
+	"monkey-go/object"
+	"monkey-go/token"
+	"sort"
+)
+
+// Profile is the coverage of one source file. Everything is in source
+// order.
+type Profile struct {
+	File       string
+	Source     []byte
+	Statements []*Statement
+	Branches   []*Branch
+	Funcs      []*Func
+}
+
+// Statement is a statement and the number of times it ran.
+type Statement struct {
+	Line, Column int
+	Count        int
+}
+
+// Branch is an if, conditional or match expression with the number of
+// times each of its branches was taken: the consequence and the
+// alternative of a condition, also for an if without else, or the arms of
+// a match.
+type Branch struct {
+	Line, Column int
+	Counts       []int
+}
+
+// Func is a function literal and the number of times it was called. Name
+// is the name it is bound to by a let or const statement, "<anonymous>"
+// otherwise.
+type Func struct {
+	Name         string
+	Line, Column int // of the fn keyword
+	Count        int
+}
+
+type position struct{ line, column int }
+
+func positionOf(tok token.Token) position {
+	return position{tok.Line, tok.Column}
+}
+
+// A Collector measures the coverage of one program. It implements
+// evaluator.BranchHook.
+type Collector struct {
+	profile    *Profile
+	statements map[position]*Statement
+	branches   map[position]*Branch
+	funcs      map[position]*Func // by the position of the function body
+}
+
+// New returns a collector for program, parsed from src, the contents of
+// file.
+func New(file string, src []byte, program ast.Node) *Collector {
+	c := &Collector{
+		profile:    &Profile{File: file, Source: src},
+		statements: map[position]*Statement{},
+		branches:   map[position]*Branch{},
+		funcs:      map[position]*Func{},
+	}
+
+	// Modify visits a function literal before the statement binding it.
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.BlockStatement:
+			// not a statement of its own
+
+		case ast.Statement:
+			pos := positionOf(ast.Start(node))
+			c.statements[pos] = &Statement{Line: pos.line, Column: pos.column}
+			if let, ok := node.(*ast.LetStatement); ok && let.Name != nil {
+				if fl, ok := let.Value.(*ast.FunctionLiteral); ok && c.funcs[positionOf(fl.Body.Token)] != nil {
+					c.funcs[positionOf(fl.Body.Token)].Name = let.Name.Value
+				}
+			}
+
+		case *ast.IfExpression:
+			c.addBranch(node.Token, 2)
+		case *ast.ConditionalExpression:
+			c.addBranch(node.Token, 2)
+		case *ast.MatchExpression:
+			c.addBranch(node.Token, len(node.Arms))
+
+		case *ast.FunctionLiteral:
+			c.funcs[positionOf(node.Body.Token)] = &Func{
+				Name:   "<anonymous>",
+				Line:   node.Token.Line,
+				Column: node.Token.Column,
+			}
+		}
+		return node
+	})
+	return c
+}
+
+func (c *Collector) addBranch(tok token.Token, n int) {
+	c.branches[positionOf(tok)] = &Branch{Line: tok.Line, Column: tok.Column, Counts: make([]int, n)}
+}
+
+// Run evaluates program in env under the collector and returns its result.
+func (c *Collector) Run(program ast.Node, env *object.Environment) object.Object {
+	old := evaluator.SetHook(c)
+	defer evaluator.SetHook(old)
+	return evaluator.Eval(program, env)
+}
+
+func (c *Collector) Statement(stmt ast.Statement, env *object.Environment) *object.Error {
+	// code that is not part of the program, such as quoted code, is not
+	// measured
+	if s := c.statements[positionOf(ast.Start(stmt))]; s != nil {
+		s.Count++
+	}
+	return nil
+}
+
+func (c *Collector) Enter(call *ast.CallExpression, fn *object.Function, env *object.Environment) {
+	if f := c.funcs[positionOf(fn.Body.Token)]; f != nil {
+		f.Count++
+	}
+}
+
+func (c *Collector) Leave(call *ast.CallExpression, fn *object.Function, result object.Object) {}
+
+func (c *Collector) Branch(node ast.Expression, i int) {
+	var tok token.Token
+	switch node := node.(type) {
+	case *ast.IfExpression:
+		tok = node.Token
+	case *ast.ConditionalExpression:
+		tok = node.Token
+	case *ast.MatchExpression:
+		tok = node.Token
+	}
+	if b := c.branches[positionOf(tok)]; b != nil && i < len(b.Counts) {
+		b.Counts[i]++
+	}
+}
+
+// Profile returns the coverage measured so far.
+func (c *Collector) Profile() *Profile {
+	p := *c.profile
+	p.Statements = sorted(c.statements)
+	p.Branches = sorted(c.branches)
+	p.Funcs = sorted(c.funcs)
+	return &p
+}
+
+func sorted[T any](m map[position]*T) []*T {
+	positions := make([]position, 0, len(m))
+	for pos := range m {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].line != positions[j].line {
+			return positions[i].line < positions[j].line
+		}
+		return positions[i].column < positions[j].column
+	})
+
+	values := make([]*T, len(positions))
+	for i, pos := range positions {
+		values[i] = m[pos]
+	}
+	return values
+}
+
+// StatementsCovered returns the number of statements that ran and the
+// number of statements.
+func (p *Profile) StatementsCovered() (covered, total int) {
+	for _, s := range p.Statements {
+		if s.Count > 0 {
+			covered++
+		}
+	}
+	return covered, len(p.Statements)
+}
+
+// BranchesCovered returns the number of branches taken and the number of
+// branches.
+func (p *Profile) BranchesCovered() (covered, total int) {
+	for _, b := range p.Branches {
+		for _, n := range b.Counts {
+			if n > 0 {
+				covered++
+			}
+		}
+		total += len(b.Counts)
+	}
+	return covered, total
+}
+
+// FuncsCovered returns the number of functions called and the number of
+// functions.
+func (p *Profile) FuncsCovered() (covered, total int) {
+	for _, f := range p.Funcs {
+		if f.Count > 0 {
+			covered++
+		}
+	}
+	return covered, len(p.Funcs)
+}
+
+// Summary describes the coverage in a line, e.g.
+// "75.0% of statements, 50.0% of branches, 1 of 2 functions".
+func (p *Profile) Summary() string {
+	funcs, total := p.FuncsCovered()
+	return fmt.Sprintf("%s of statements, %s of branches, %d of %d functions",
+		percent(p.StatementsCovered()), percent(p.BranchesCovered()), funcs, total)
+}
+
+func percent(covered, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(covered)/float64(total))
+}
@@ -0,0 +1,154 @@
+package cover
+
+import (
+	"bytes"
+	"fmt"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"strings"
+	"testing"
+)
+
+const input = `let sign = fn(n) {
+    if (n < 0) {
+        -1
+    } else {
+        n == 0 ? 0 : 1
+    }
+};
+let describe = fn(x) {
+    match (x) { 0 => "zero", _ => "other" }
+};
+let unused = fn() { 42 };
+sign(5) + sign(3);
+describe(0)`
+
+func collect(t *testing.T, input string) *Profile {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	c := New("test.monkey", []byte(input), program)
+	if result := c.Run(program, object.NewEnvironment()); result.Inspect() != "zero" {
+		t.Fatalf("wrong result. got=%s", result.Inspect())
+	}
+	return c.Profile()
+}
+
+func TestProfile(t *testing.T) {
+	p := collect(t, input)
+
+	var got []string
+	for _, s := range p.Statements {
+		got = append(got, fmt.Sprintf("%d:%d=%d", s.Line, s.Column, s.Count))
+	}
+	expected := "1:1=1 2:5=2 3:9=0 5:9=2 8:1=1 9:5=1 11:1=1 11:21=0 12:1=1 13:1=1"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("wrong statements.\nwant=%s\ngot=%s", expected, strings.Join(got, " "))
+	}
+
+	got = nil
+	for _, b := range p.Branches {
+		got = append(got, fmt.Sprintf("%d:%d=%v", b.Line, b.Column, b.Counts))
+	}
+	expected = "2:5=[0 2] 5:16=[0 2] 9:5=[1 0]"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("wrong branches.\nwant=%s\ngot=%s", expected, strings.Join(got, " "))
+	}
+
+	got = nil
+	for _, f := range p.Funcs {
+		got = append(got, fmt.Sprintf("%s %d:%d=%d", f.Name, f.Line, f.Column, f.Count))
+	}
+	expected = "sign 1:12=2 describe 8:16=1 unused 11:14=0"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("wrong functions.\nwant=%s\ngot=%s", expected, strings.Join(got, " "))
+	}
+
+	summary := "80.0% of statements, 50.0% of branches, 2 of 3 functions"
+	if p.Summary() != summary {
+		t.Errorf("wrong summary.\nwant=%s\ngot=%s", summary, p.Summary())
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	var out bytes.Buffer
+	other := collect(t, "fn(x) { x }(\"zero\")")
+	other.File = "other.monkey"
+	if err := WriteLCOV(&out, []*Profile{collect(t, input), other}); err != nil {
+		t.Fatalf("WriteLCOV: %v", err)
+	}
+
+	expected := `TN:
+SF:test.monkey
+FN:1,sign
+FN:8,describe
+FN:11,unused
+FNDA:2,sign
+FNDA:1,describe
+FNDA:0,unused
+FNF:3
+FNH:2
+BRDA:2,0,0,0
+BRDA:2,0,1,2
+BRDA:5,0,0,0
+BRDA:5,0,1,2
+BRDA:9,0,0,1
+BRDA:9,0,1,0
+BRF:6
+BRH:3
+DA:1,1
+DA:2,2
+DA:3,0
+DA:5,2
+DA:8,1
+DA:9,1
+DA:11,1
+DA:12,1
+DA:13,1
+LF:9
+LH:8
+end_of_record
+TN:
+SF:other.monkey
+FN:1,<anonymous>:1:1
+FNDA:1,<anonymous>:1:1
+FNF:1
+FNH:1
+BRF:0
+BRH:0
+DA:1,1
+LF:1
+LH:1
+end_of_record
+`
+	if out.String() != expected {
+		t.Errorf("wrong tracefile.\nwant=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteHTML(&out, []*Profile{collect(t, input)}); err != nil {
+		t.Fatalf("WriteHTML: %v", err)
+	}
+
+	for _, line := range []string{
+		"<h2>test.monkey</h2>",
+		"<p>80.0% of statements, 50.0% of branches, 2 of 3 functions</p>",
+		`<span class="line">1</span><span class="count">1</span>  <span class="hit">let sign = fn(n) {</span>`,
+		`<span class="line">2</span><span class="count">2</span>  <span class="partial" title="1 branch never taken">    if (n &lt; 0) {</span>`,
+		`<span class="line">3</span><span class="count">0</span>  <span class="miss">        -1</span>`,
+		`<span class="line">4</span><span class="count"></span>      } else {`,
+		`<span class="line">11</span><span class="count">1</span>  <span class="partial">let unused = fn() { 42 };</span>`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("line %q missing from\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// WriteLCOV writes profiles as an LCOV tracefile, the format read by
+// genhtml and most coverage services. A line counts as run as often as its
+// most frequently run statement.
+func WriteLCOV(w io.Writer, profiles []*Profile) error {
+	bw := bufio.NewWriter(w)
+	for _, p := range profiles {
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", p.File)
+
+		hit := 0
+		for _, f := range p.Funcs {
+			fmt.Fprintf(bw, "FN:%d,%s\n", f.Line, funcName(f))
+		}
+		for _, f := range p.Funcs {
+			fmt.Fprintf(bw, "FNDA:%d,%s\n", f.Count, funcName(f))
+			if f.Count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "FNF:%d\nFNH:%d\n", len(p.Funcs), hit)
+
+		// the branch points of a line are numbered from 0 as blocks
+		taken, block, line := 0, 0, 0
+		for _, b := range p.Branches {
+			if b.Line != line {
+				block, line = 0, b.Line
+			}
+			reached := false
+			for _, n := range b.Counts {
+				reached = reached || n > 0
+			}
+			for i, n := range b.Counts {
+				count := "-"
+				if reached {
+					count = fmt.Sprint(n)
+				}
+				if n > 0 {
+					taken++
+				}
+				fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", b.Line, block, i, count)
+			}
+			block++
+		}
+		_, branches := p.BranchesCovered()
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", branches, taken)
+
+		lines := lineCounts(p)
+		hit = 0
+		for _, l := range lines {
+			fmt.Fprintf(bw, "DA:%d,%d\n", l.line, l.count)
+			if l.count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+	return bw.Flush()
+}
+
+// funcName names f uniquely within its file.
+func funcName(f *Func) string {
+	if f.Name == "<anonymous>" {
+		return fmt.Sprintf("<anonymous>:%d:%d", f.Line, f.Column)
+	}
+	return f.Name
+}
+
+type lineCount struct {
+	line, count int
+	missed      bool // a statement of the line did not run
+}
+
+// lineCounts returns the lines with a statement in order.
+func lineCounts(p *Profile) []lineCount {
+	var lines []lineCount
+	for _, s := range p.Statements {
+		if len(lines) == 0 || lines[len(lines)-1].line != s.Line {
+			lines = append(lines, lineCount{line: s.Line})
+		}
+		l := &lines[len(lines)-1]
+		if s.Count > l.count {
+			l.count = s.Count
+		}
+		if s.Count == 0 {
+			l.missed = true
+		}
+	}
+	return lines
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Monkey coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { line-height: 1.3; }
+.count, .line { display: inline-block; width: 4em; text-align: right; color: #888; }
+.hit { background: #dfd; }
+.partial { background: #ffd; }
+.miss { background: #fdd; }
+</style>
+</head>
+<body>
+`
+
+// WriteHTML writes profiles as an HTML page showing the source of each
+// file. Lines whose statements and branches all ran are green, lines where
+// some of them did not are yellow, and lines where none of them did are
+// red. The number of runs precedes each line.
+func WriteHTML(w io.Writer, profiles []*Profile) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(htmlHeader)
+
+	for _, p := range profiles {
+		fmt.Fprintf(bw, "<h2>%s</h2>\n<p>%s</p>\n<pre>\n",
+			html.EscapeString(p.File), html.EscapeString(p.Summary()))
+
+		lines := map[int]lineCount{}
+		for _, l := range lineCounts(p) {
+			lines[l.line] = l
+		}
+		untaken := map[int]int{} // branches never taken by line
+		reached := map[int]bool{}
+		for _, b := range p.Branches {
+			for _, n := range b.Counts {
+				if n == 0 {
+					untaken[b.Line]++
+				} else {
+					reached[b.Line] = true
+				}
+			}
+		}
+
+		for i, src := range strings.Split(string(p.Source), "\n") {
+			n := i + 1
+			l, ok := lines[n]
+			class, count, title := "", "", ""
+			switch {
+			case !ok && untaken[n] == 0:
+			case l.count == 0 && !reached[n]:
+				class, count = "miss", "0"
+			case l.missed || untaken[n] > 0:
+				class, count = "partial", fmt.Sprint(l.count)
+			default:
+				class, count = "hit", fmt.Sprint(l.count)
+			}
+			if untaken[n] > 0 {
+				title = fmt.Sprintf(" title=\"%d branches never taken\"", untaken[n])
+				if untaken[n] == 1 {
+					title = ` title="1 branch never taken"`
+				}
+			}
+
+			fmt.Fprintf(bw, `<span class="line">%d</span><span class="count">%s</span>  `, n, count)
+			if class == "" {
+				fmt.Fprintf(bw, "%s\n", html.EscapeString(src))
+				continue
+			}
+			fmt.Fprintf(bw, "<span class=\"%s\"%s>%s</span>\n", class, title, html.EscapeString(src))
+		}
+		bw.WriteString("</pre>\n")
+	}
+
+	bw.WriteString("</body>\n</html>\n")
+	return bw.Flush()
+}
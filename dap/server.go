@@ -0,0 +1,397 @@
+// Package dap implements a Debug Adapter Protocol server for Monkey, used by
+// `monkey dap`. It launches one program per session under a
+// debugger.Controller and speaks the protocol over a pair of streams,
+// usually the standard input and output of the adapter process.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"monkey-go/ast"
+	"monkey-go/debugger"
+	"monkey-go/diagnostic"
+	"monkey-go/evaluator"
+	"monkey-go/internal/framing"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	errNotLaunched = errors.New("no program launched")
+	errNotPaused   = errors.New("the program is not paused")
+)
+
+// A server serves one debug session. Requests are handled one at a time on
+// the goroutine of Serve while the program runs on its own goroutine,
+// blocked in pausedAt whenever it is paused.
+type server struct {
+	mu       sync.Mutex // guards the fields below and writes to out
+	out      io.Writer
+	seq      int
+	err      error // the first error writing to out
+	paused   bool
+	stopping bool
+
+	path    string
+	program ast.Node
+	lines   map[int]bool // the lines on which a breakpoint can pause
+	c       *debugger.Controller
+	resume  chan bool     // resumes a paused program, or stops it if false
+	done    chan struct{} // closed when the program ends, nil before it starts
+	then    func()        // run after the response to the current request
+	refs    []any         // variable references, see reference
+}
+
+// Serve reads requests from in and writes responses and events to out until
+// the client disconnects or in ends. A running program is stopped before
+// Serve returns.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{out: out, c: debugger.NewController(), resume: make(chan bool)}
+	s.c.Paused = s.pausedAt
+	defer s.stop()
+
+	r := bufio.NewReader(in)
+	for {
+		body, err := framing.Read(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("malformed message: %v", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		result, err := s.request(&req)
+		s.respond(&req, result, err)
+		if then := s.then; then != nil {
+			s.then = nil
+			then()
+		}
+
+		s.mu.Lock()
+		err = s.err
+		s.mu.Unlock()
+		if err != nil || req.Command == "disconnect" {
+			return err
+		}
+	}
+}
+
+// send writes msg, numbering it. Only the first write error is kept.
+func (s *server) send(msg any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendLocked(msg)
+}
+
+func (s *server) sendLocked(msg any) {
+	if s.err != nil {
+		return
+	}
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.err = framing.Write(s.out, body)
+}
+
+func (s *server) respond(req *request, body any, err error) {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: true, Body: body}
+	if err != nil {
+		resp.Success, resp.Message, resp.Body = false, err.Error(), nil
+	}
+	s.send(resp)
+}
+
+func (s *server) event(name string, body any) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+func decode(args json.RawMessage, v any) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+func (s *server) request(req *request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		}, nil
+
+	case "launch":
+		var args LaunchArguments
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(args)
+
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args)
+
+	case "configurationDone":
+		if s.program == nil {
+			return nil, errNotLaunched
+		}
+		if s.done == nil {
+			s.then = s.start
+		}
+		return nil, nil
+
+	case "threads":
+		return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+
+	case "stackTrace":
+		var args StackTraceArguments
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.stackTrace(args)
+
+	case "scopes":
+		var args ScopesArguments
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.scopes(args)
+
+	case "variables":
+		var args VariablesArguments
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args)
+
+	case "evaluate":
+		var args EvaluateArguments
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.evaluate(args)
+
+	case "continue":
+		return ContinueResponseBody{AllThreadsContinued: true}, s.step(s.c.Continue)
+
+	case "next":
+		return nil, s.step(s.c.Next)
+
+	case "stepIn":
+		return nil, s.step(s.c.Step)
+
+	case "stepOut":
+		return nil, s.step(func() {
+			// out of the program itself is to its end
+			if !s.c.Out() {
+				s.c.Continue()
+			}
+		})
+
+	case "pause":
+		// the stopped event follows the response
+		s.then = s.c.Pause
+		return nil, nil
+
+	case "terminate", "disconnect":
+		s.stop()
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported command: %s", req.Command)
+}
+
+// launch loads the program. It starts when the configuration is done,
+// which the client is told it can send by the initialized event.
+func (s *server) launch(args LaunchArguments) error {
+	if s.program != nil {
+		return errors.New("a program is already launched")
+	}
+	src, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+
+	printer := &diagnostic.Printer{File: args.Program, Source: string(src)}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return render(printer, diagnostic.FromParser(p.ErrorList())...)
+	}
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, merr := evaluator.ExpandMacros(program, macroEnv)
+	if merr != nil {
+		return render(printer, diagnostic.FromError(merr))
+	}
+
+	s.path, s.program = args.Program, expanded
+	s.lines = debugger.StatementLines(program)
+	if !args.StopOnEntry {
+		s.c.Continue()
+	}
+	s.then = func() { s.event("initialized", nil) }
+	return nil
+}
+
+func (s *server) setBreakpoints(args SetBreakpointsArguments) (any, error) {
+	if s.program == nil {
+		return nil, errNotLaunched
+	}
+
+	samePath := args.Source.Path == s.path
+	if abs, err := filepath.Abs(args.Source.Path); err == nil {
+		if launched, err := filepath.Abs(s.path); err == nil {
+			samePath = abs == launched
+		}
+	}
+
+	s.c.ClearBreakpoints()
+	breakpoints := make([]Breakpoint, len(args.Breakpoints))
+	for i, bp := range args.Breakpoints {
+		breakpoints[i].Line = bp.Line
+		switch {
+		case !samePath:
+			breakpoints[i].Message = "not in the launched program"
+		case !s.lines[bp.Line]:
+			breakpoints[i].Message = "no statement on this line"
+		default:
+			breakpoints[i].Verified = true
+			s.c.SetBreakpoint(bp.Line)
+		}
+	}
+	return SetBreakpointsResponseBody{Breakpoints: breakpoints}, nil
+}
+
+// render returns an error with the diagnostics as printer prints them.
+func render(printer *diagnostic.Printer, diagnostics ...diagnostic.Diagnostic) error {
+	var out strings.Builder
+	for _, d := range diagnostics {
+		printer.Print(&out, d) // nolint
+	}
+	return errors.New(strings.TrimSuffix(out.String(), "\n"))
+}
+
+// start runs the program on its own goroutine. Its output is sent to the
+// client as output events.
+func (s *server) start() {
+	s.done = make(chan struct{})
+	old := evaluator.Stdout
+	evaluator.Stdout = output{s}
+
+	go func() {
+		defer close(s.done)
+		result := s.c.Run(s.program, object.NewEnvironment())
+		evaluator.Stdout = old
+
+		exitCode := 0
+		if errObj, ok := result.(*object.Error); ok {
+			exitCode = 1
+			if errObj != debugger.ErrStopped {
+				s.event("output", OutputEventBody{Category: "stderr", Output: errObj.Inspect() + "\n"})
+			}
+		}
+		s.event("exited", ExitedEventBody{ExitCode: exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+// pausedAt is the Paused callback of the controller. It runs on the
+// goroutine of the program and blocks it until a request resumes it.
+func (s *server) pausedAt(reason string) bool {
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return false
+	}
+	s.paused = true
+	s.sendLocked(&event{Type: "event", Event: "stopped", Body: StoppedEventBody{
+		Reason:            reason,
+		ThreadID:          threadID,
+		AllThreadsStopped: true,
+	}})
+	s.mu.Unlock()
+
+	return <-s.resume
+}
+
+// isPaused reports whether the program is paused. The state of a paused
+// program can be read until it is resumed by the goroutine of Serve.
+func (s *server) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// step sets how far a paused program runs with move and resumes it after
+// the response.
+func (s *server) step(move func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused {
+		return errNotPaused
+	}
+	s.paused = false
+	s.refs = nil
+	move()
+	s.then = func() { s.resume <- true }
+	return nil
+}
+
+// stop stops the program, if it runs, and waits for it to end.
+func (s *server) stop() {
+	if s.done == nil {
+		return
+	}
+	s.c.Stop()
+	s.mu.Lock()
+	s.stopping = true
+	paused := s.paused
+	s.paused = false
+	s.mu.Unlock()
+	if paused {
+		s.resume <- false
+	}
+	<-s.done
+}
+
+// output sends what the program prints as output events.
+type output struct{ s *server }
+
+func (o output) Write(p []byte) (int, error) {
+	o.s.event("output", OutputEventBody{Category: "stdout", Output: string(p)})
+	return len(p), nil
+}
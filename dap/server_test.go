@@ -0,0 +1,280 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey-go/internal/framing"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// replay runs a session recorded as a transcript. Lines starting with "->"
+// are requests, a command and its arguments, numbered from 1 in order.
+// Lines starting with "<-" are the messages the adapter must send, in
+// order, without their seq. Every message expected after a request is read
+// before the next one is sent, so a transcript is deterministic even though
+// the program runs on a goroutine of its own. $PROGRAM is replaced by the
+// path of a file holding src.
+func replay(t *testing.T, src, transcript string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.monkey")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	transcript = strings.ReplaceAll(transcript, "$PROGRAM", path)
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(inR, outW)
+		outW.Close()
+	}()
+	defer func() {
+		inW.Close()
+		go io.Copy(io.Discard, outR)
+		if err := <-served; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	}()
+
+	r := bufio.NewReader(outR)
+	seq := 0
+	for n, line := range strings.Split(strings.TrimSpace(transcript), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "->"):
+			command, args, _ := strings.Cut(strings.TrimSpace(line[2:]), " ")
+			seq++
+			msg := map[string]any{"seq": seq, "type": "request", "command": command}
+			if args != "" {
+				msg["arguments"] = json.RawMessage(args)
+			}
+			body, err := json.Marshal(msg)
+			if err != nil {
+				t.Fatalf("line %d: json.Marshal: %v", n+1, err)
+			}
+			if err := framing.Write(inW, body); err != nil {
+				t.Fatalf("line %d: writing request: %v", n+1, err)
+			}
+
+		case strings.HasPrefix(line, "<-"):
+			var want any
+			if err := json.Unmarshal([]byte(line[2:]), &want); err != nil {
+				t.Fatalf("line %d: json.Unmarshal: %v", n+1, err)
+			}
+			body, err := framing.Read(r)
+			if err != nil {
+				t.Fatalf("line %d: reading message: %v", n+1, err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("line %d: json.Unmarshal(%s): %v", n+1, body, err)
+			}
+			delete(got, "seq")
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("line %d: wrong message.\nwant=%s\ngot=%s", n+1, line[2:], body)
+			}
+
+		default:
+			t.Fatalf("line %d: malformed transcript line %q", n+1, line)
+		}
+	}
+}
+
+const program = `let add = fn(a, b) {
+    let sum = a + b;
+    sum
+};
+let xs = [1, add(1, 2)];
+print(xs);
+add(xs[1], 4)`
+
+func TestBreakpoints(t *testing.T) {
+	replay(t, program, `
+-> initialize {"adapterID": "monkey"}
+<- {"type": "response", "request_seq": 1, "success": true, "command": "initialize", "body": {"supportsConfigurationDoneRequest": true, "supportsEvaluateForHovers": true, "supportsTerminateRequest": true}}
+-> launch {"program": "$PROGRAM"}
+<- {"type": "response", "request_seq": 2, "success": true, "command": "launch"}
+<- {"type": "event", "event": "initialized"}
+-> setBreakpoints {"source": {"path": "$PROGRAM"}, "breakpoints": [{"line": 2}, {"line": 4}]}
+<- {"type": "response", "request_seq": 3, "success": true, "command": "setBreakpoints", "body": {"breakpoints": [{"verified": true, "line": 2}, {"verified": false, "line": 4, "message": "no statement on this line"}]}}
+-> configurationDone
+<- {"type": "response", "request_seq": 4, "success": true, "command": "configurationDone"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "breakpoint", "threadId": 1, "allThreadsStopped": true}}
+-> threads
+<- {"type": "response", "request_seq": 5, "success": true, "command": "threads", "body": {"threads": [{"id": 1, "name": "main"}]}}
+-> stackTrace {"threadId": 1}
+<- {"type": "response", "request_seq": 6, "success": true, "command": "stackTrace", "body": {"stackFrames": [{"id": 1, "name": "add", "source": {"name": "test.monkey", "path": "$PROGRAM"}, "line": 2, "column": 1}, {"id": 2, "name": "<program>", "source": {"name": "test.monkey", "path": "$PROGRAM"}, "line": 5, "column": 1}], "totalFrames": 2}}
+-> scopes {"frameId": 1}
+<- {"type": "response", "request_seq": 7, "success": true, "command": "scopes", "body": {"scopes": [{"name": "Locals", "variablesReference": 1, "expensive": false}, {"name": "Globals", "variablesReference": 2, "expensive": false}]}}
+-> variables {"variablesReference": 1}
+<- {"type": "response", "request_seq": 8, "success": true, "command": "variables", "body": {"variables": [{"name": "a", "value": "1", "type": "INTEGER", "variablesReference": 0}, {"name": "b", "value": "2", "type": "INTEGER", "variablesReference": 0}]}}
+-> variables {"variablesReference": 2}
+<- {"type": "response", "request_seq": 9, "success": true, "command": "variables", "body": {"variables": [{"name": "add", "value": "fn(a, b) { ... }", "type": "FUNCTION", "variablesReference": 0}]}}
+-> evaluate {"expression": "a + b * 10", "frameId": 1, "context": "repl"}
+<- {"type": "response", "request_seq": 10, "success": true, "command": "evaluate", "body": {"result": "21", "type": "INTEGER", "variablesReference": 0}}
+-> evaluate {"expression": "let", "context": "repl"}
+<- {"type": "response", "request_seq": 11, "success": false, "command": "evaluate", "message": "expected next token to be IDENT, got EOF instead."}
+-> continue {"threadId": 1}
+<- {"type": "response", "request_seq": 12, "success": true, "command": "continue", "body": {"allThreadsContinued": true}}
+<- {"type": "event", "event": "output", "body": {"category": "stdout", "output": "[1, 3]\n"}}
+<- {"type": "event", "event": "stopped", "body": {"reason": "breakpoint", "threadId": 1, "allThreadsStopped": true}}
+-> evaluate {"expression": "[a, b]", "frameId": 1, "context": "watch"}
+<- {"type": "response", "request_seq": 13, "success": true, "command": "evaluate", "body": {"result": "[3, 4]", "type": "ARRAY", "variablesReference": 1}}
+-> variables {"variablesReference": 1}
+<- {"type": "response", "request_seq": 14, "success": true, "command": "variables", "body": {"variables": [{"name": "[0]", "value": "3", "type": "INTEGER", "variablesReference": 0}, {"name": "[1]", "value": "4", "type": "INTEGER", "variablesReference": 0}]}}
+-> variables {"variablesReference": 2}
+<- {"type": "response", "request_seq": 15, "success": false, "command": "variables", "message": "invalid variables reference"}
+-> evaluate {"expression": "xs", "frameId": 2, "context": "hover"}
+<- {"type": "response", "request_seq": 16, "success": true, "command": "evaluate", "body": {"result": "[1, 3]", "type": "ARRAY", "variablesReference": 2}}
+-> stepOut {"threadId": 1}
+<- {"type": "response", "request_seq": 17, "success": true, "command": "stepOut"}
+<- {"type": "event", "event": "exited", "body": {"exitCode": 0}}
+<- {"type": "event", "event": "terminated"}
+-> disconnect
+<- {"type": "response", "request_seq": 18, "success": true, "command": "disconnect"}
+`)
+}
+
+func TestStepping(t *testing.T) {
+	src := `let xs = [1, [2, 3]];
+let h = {"a": xs};
+let f = fn(x) {
+    x + true
+};
+f(1)`
+
+	replay(t, src, `
+-> initialize
+<- {"type": "response", "request_seq": 1, "success": true, "command": "initialize", "body": {"supportsConfigurationDoneRequest": true, "supportsEvaluateForHovers": true, "supportsTerminateRequest": true}}
+-> stackTrace {"threadId": 1}
+<- {"type": "response", "request_seq": 2, "success": false, "command": "stackTrace", "message": "the program is not paused"}
+-> configurationDone
+<- {"type": "response", "request_seq": 3, "success": false, "command": "configurationDone", "message": "no program launched"}
+-> launch {"program": "$PROGRAM", "stopOnEntry": true}
+<- {"type": "response", "request_seq": 4, "success": true, "command": "launch"}
+<- {"type": "event", "event": "initialized"}
+-> configurationDone
+<- {"type": "response", "request_seq": 5, "success": true, "command": "configurationDone"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "entry", "threadId": 1, "allThreadsStopped": true}}
+-> next {"threadId": 1}
+<- {"type": "response", "request_seq": 6, "success": true, "command": "next"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "step", "threadId": 1, "allThreadsStopped": true}}
+-> next {"threadId": 1}
+<- {"type": "response", "request_seq": 7, "success": true, "command": "next"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "step", "threadId": 1, "allThreadsStopped": true}}
+-> scopes {"frameId": 1}
+<- {"type": "response", "request_seq": 8, "success": true, "command": "scopes", "body": {"scopes": [{"name": "Globals", "variablesReference": 1, "expensive": false}]}}
+-> variables {"variablesReference": 1}
+<- {"type": "response", "request_seq": 9, "success": true, "command": "variables", "body": {"variables": [{"name": "h", "value": "{a: [1, [2, 3]]}", "type": "HASHMAP", "variablesReference": 2}, {"name": "xs", "value": "[1, [2, 3]]", "type": "ARRAY", "variablesReference": 3}]}}
+-> variables {"variablesReference": 2}
+<- {"type": "response", "request_seq": 10, "success": true, "command": "variables", "body": {"variables": [{"name": "a", "value": "[1, [2, 3]]", "type": "ARRAY", "variablesReference": 4}]}}
+-> variables {"variablesReference": 3}
+<- {"type": "response", "request_seq": 11, "success": true, "command": "variables", "body": {"variables": [{"name": "[0]", "value": "1", "type": "INTEGER", "variablesReference": 0}, {"name": "[1]", "value": "[2, 3]", "type": "ARRAY", "variablesReference": 5}]}}
+-> next {"threadId": 1}
+<- {"type": "response", "request_seq": 12, "success": true, "command": "next"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "step", "threadId": 1, "allThreadsStopped": true}}
+-> stepIn {"threadId": 1}
+<- {"type": "response", "request_seq": 13, "success": true, "command": "stepIn"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "step", "threadId": 1, "allThreadsStopped": true}}
+-> stackTrace {"threadId": 1, "levels": 1}
+<- {"type": "response", "request_seq": 14, "success": true, "command": "stackTrace", "body": {"stackFrames": [{"id": 1, "name": "f", "source": {"name": "test.monkey", "path": "$PROGRAM"}, "line": 4, "column": 1}], "totalFrames": 2}}
+-> frobnicate
+<- {"type": "response", "request_seq": 15, "success": false, "command": "frobnicate", "message": "unsupported command: frobnicate"}
+-> continue {"threadId": 1}
+<- {"type": "response", "request_seq": 16, "success": true, "command": "continue", "body": {"allThreadsContinued": true}}
+<- {"type": "event", "event": "output", "body": {"category": "stderr", "output": "ERROR: type mismatch: INTEGER + BOOLEAN\n"}}
+<- {"type": "event", "event": "exited", "body": {"exitCode": 1}}
+<- {"type": "event", "event": "terminated"}
+-> continue {"threadId": 1}
+<- {"type": "response", "request_seq": 17, "success": false, "command": "continue", "message": "the program is not paused"}
+`)
+}
+
+func TestPauseAndDisconnect(t *testing.T) {
+	// the tail call runs forever; stepping to the call first makes sure
+	// that the pause, whenever it comes, is in the body of loop
+	src := `let loop = fn(n) {
+    loop(n + 1)
+};
+loop(0)`
+
+	replay(t, src, `
+-> launch {"program": "$PROGRAM", "stopOnEntry": true}
+<- {"type": "response", "request_seq": 1, "success": true, "command": "launch"}
+<- {"type": "event", "event": "initialized"}
+-> configurationDone
+<- {"type": "response", "request_seq": 2, "success": true, "command": "configurationDone"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "entry", "threadId": 1, "allThreadsStopped": true}}
+-> next {"threadId": 1}
+<- {"type": "response", "request_seq": 3, "success": true, "command": "next"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "step", "threadId": 1, "allThreadsStopped": true}}
+-> continue {"threadId": 1}
+<- {"type": "response", "request_seq": 4, "success": true, "command": "continue", "body": {"allThreadsContinued": true}}
+-> pause {"threadId": 1}
+<- {"type": "response", "request_seq": 5, "success": true, "command": "pause"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "pause", "threadId": 1, "allThreadsStopped": true}}
+-> stackTrace {"threadId": 1}
+<- {"type": "response", "request_seq": 6, "success": true, "command": "stackTrace", "body": {"stackFrames": [{"id": 1, "name": "loop", "source": {"name": "test.monkey", "path": "$PROGRAM"}, "line": 2, "column": 1}, {"id": 2, "name": "<program>", "source": {"name": "test.monkey", "path": "$PROGRAM"}, "line": 4, "column": 1}], "totalFrames": 2}}
+-> evaluate {"expression": "n > -1", "frameId": 1, "context": "watch"}
+<- {"type": "response", "request_seq": 7, "success": true, "command": "evaluate", "body": {"result": "true", "type": "BOOLEAN", "variablesReference": 0}}
+-> disconnect
+<- {"type": "event", "event": "exited", "body": {"exitCode": 1}}
+<- {"type": "event", "event": "terminated"}
+<- {"type": "response", "request_seq": 8, "success": true, "command": "disconnect"}
+`)
+}
+
+func TestPauseInEval(t *testing.T) {
+	// the code run by eval runs forever; a pause in it is shown at the call
+	// of eval, and disconnecting stops it
+	src := `let x = 1;
+eval("let f = fn(n) { f(n + 1) }; f(0)")`
+
+	replay(t, src, `
+-> launch {"program": "$PROGRAM", "stopOnEntry": true}
+<- {"type": "response", "request_seq": 1, "success": true, "command": "launch"}
+<- {"type": "event", "event": "initialized"}
+-> configurationDone
+<- {"type": "response", "request_seq": 2, "success": true, "command": "configurationDone"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "entry", "threadId": 1, "allThreadsStopped": true}}
+-> next {"threadId": 1}
+<- {"type": "response", "request_seq": 3, "success": true, "command": "next"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "step", "threadId": 1, "allThreadsStopped": true}}
+-> continue {"threadId": 1}
+<- {"type": "response", "request_seq": 4, "success": true, "command": "continue", "body": {"allThreadsContinued": true}}
+-> pause {"threadId": 1}
+<- {"type": "response", "request_seq": 5, "success": true, "command": "pause"}
+<- {"type": "event", "event": "stopped", "body": {"reason": "pause", "threadId": 1, "allThreadsStopped": true}}
+-> stackTrace {"threadId": 1}
+<- {"type": "response", "request_seq": 6, "success": true, "command": "stackTrace", "body": {"stackFrames": [{"id": 1, "name": "<program>", "source": {"name": "test.monkey", "path": "$PROGRAM"}, "line": 2, "column": 1}], "totalFrames": 1}}
+-> continue {"threadId": 1}
+<- {"type": "response", "request_seq": 7, "success": true, "command": "continue", "body": {"allThreadsContinued": true}}
+-> disconnect
+<- {"type": "event", "event": "exited", "body": {"exitCode": 1}}
+<- {"type": "event", "event": "terminated"}
+<- {"type": "response", "request_seq": 8, "success": true, "command": "disconnect"}
+`)
+}
+
+func TestLaunchErrors(t *testing.T) {
+	replay(t, "let = 1;", `
+-> launch {"program": "$PROGRAM"}
+<- {"type": "response", "request_seq": 1, "success": false, "command": "launch", "message": "error: expected next token to be IDENT, got = instead.\n --> $PROGRAM:1:5\n  |\n1 | let = 1;\n  |     ^"}
+-> setBreakpoints {"source": {"path": "$PROGRAM"}, "breakpoints": [{"line": 1}]}
+<- {"type": "response", "request_seq": 2, "success": false, "command": "setBreakpoints", "message": "no program launched"}
+`)
+
+	missing := filepath.Join(t.TempDir(), "missing.monkey")
+	replay(t, "", fmt.Sprintf(`
+-> launch {"program": %q}
+<- {"type": "response", "request_seq": 1, "success": false, "command": "launch", "message": "open %s: no such file or directory"}
+`, missing, missing))
+}
@@ -0,0 +1,169 @@
+package dap
+
+import (
+	"errors"
+	"fmt"
+	"monkey-go/debugger"
+	"monkey-go/object"
+	"path/filepath"
+	"sort"
+)
+
+// Frames are numbered from 1, innermost first, as debugger.Controller.Frames
+// orders them.
+
+func (s *server) stackTrace(args StackTraceArguments) (any, error) {
+	if !s.isPaused() {
+		return nil, errNotPaused
+	}
+
+	frames := s.c.Frames()
+	from, to := args.StartFrame, len(frames)
+	if from > len(frames) {
+		from = len(frames)
+	}
+	if args.Levels > 0 && from+args.Levels < to {
+		to = from + args.Levels
+	}
+
+	source := &Source{Name: filepath.Base(s.path), Path: s.path}
+	body := StackTraceResponseBody{StackFrames: []StackFrame{}, TotalFrames: len(frames)}
+	for i := from; i < to; i++ {
+		body.StackFrames = append(body.StackFrames, StackFrame{
+			ID:     i + 1,
+			Name:   frames[i].Name,
+			Source: source,
+			Line:   frames[i].Line,
+			Column: 1,
+		})
+	}
+	return body, nil
+}
+
+func (s *server) frame(id int) (debugger.Frame, error) {
+	if !s.isPaused() {
+		return debugger.Frame{}, errNotPaused
+	}
+	frames := s.c.Frames()
+	if id < 1 || id > len(frames) {
+		return debugger.Frame{}, fmt.Errorf("no frame %d", id)
+	}
+	return frames[id-1], nil
+}
+
+// scopes lists the environments of a frame from the innermost out, walking
+// Environment.Outer. The outermost is the global scope.
+func (s *server) scopes(args ScopesArguments) (any, error) {
+	f, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	body := ScopesResponseBody{Scopes: []Scope{}}
+	for env := f.Env; env != nil; env = env.Outer() {
+		name := "Closure"
+		switch {
+		case env.Outer() == nil:
+			name = "Globals"
+		case env == f.Env:
+			name = "Locals"
+		}
+		body.Scopes = append(body.Scopes, Scope{Name: name, VariablesReference: s.reference(env)})
+	}
+	return body, nil
+}
+
+// reference returns the variables reference of v, an environment or an
+// object with elements, or 0 if v has none. References are valid until
+// the program resumes.
+func (s *server) reference(v any) int {
+	switch v := v.(type) {
+	case *object.Array:
+		if len(v.Elements) == 0 {
+			return 0
+		}
+	case *object.HashMap:
+		if len(v.Pairs) == 0 {
+			return 0
+		}
+	case *object.Environment:
+	default:
+		return 0
+	}
+	s.refs = append(s.refs, v)
+	return len(s.refs)
+}
+
+func (s *server) variables(args VariablesArguments) (any, error) {
+	if !s.isPaused() {
+		return nil, errNotPaused
+	}
+	if args.VariablesReference < 1 || args.VariablesReference > len(s.refs) {
+		return nil, errors.New("invalid variables reference")
+	}
+
+	body := VariablesResponseBody{Variables: []Variable{}}
+	switch v := s.refs[args.VariablesReference-1].(type) {
+	case *object.Environment:
+		for _, name := range v.Names() {
+			value, _ := v.Get(name)
+			variable := s.variable(name, value)
+			if v.IsConst(name) {
+				variable.PresentationHint = &VariablePresentationHint{Attributes: []string{"readOnly"}}
+			}
+			body.Variables = append(body.Variables, variable)
+		}
+
+	case *object.Array:
+		for i, elem := range v.Elements {
+			body.Variables = append(body.Variables, s.variable(fmt.Sprintf("[%d]", i), elem))
+		}
+
+	case *object.HashMap:
+		pairs := make([]object.HashPair, 0, len(v.Pairs))
+		for _, pair := range v.Pairs {
+			pairs = append(pairs, pair)
+		}
+		sort.Slice(pairs, func(i, j int) bool {
+			return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+		})
+		for _, pair := range pairs {
+			body.Variables = append(body.Variables, s.variable(pair.Key.Inspect(), pair.Value))
+		}
+	}
+	return body, nil
+}
+
+func (s *server) variable(name string, value object.Object) Variable {
+	return Variable{
+		Name:               name,
+		Value:              debugger.Inspect(value),
+		Type:               string(value.Type()),
+		VariablesReference: s.reference(value),
+	}
+}
+
+// evaluate runs an expression in a frame of the paused program, the
+// innermost if none is given.
+func (s *server) evaluate(args EvaluateArguments) (any, error) {
+	id := args.FrameID
+	if id == 0 {
+		id = 1
+	}
+	if _, err := s.frame(id); err != nil {
+		return nil, err
+	}
+
+	result, err := s.c.Evaluate(args.Expression, id-1)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return EvaluateResponseBody{}, nil
+	}
+	return EvaluateResponseBody{
+		Result:             debugger.Inspect(result),
+		Type:               string(result.Type()),
+		VariablesReference: s.reference(result),
+	}, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"monkey-go/dap"
+	"os"
+)
+
+// runDAP implements `monkey dap`, a debug adapter speaking the Debug
+// Adapter Protocol over standard input and output.
+func runDAP(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey dap")
+		return 2
+	}
+
+	if err := dap.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "monkey dap: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"monkey-go/debugger"
+	"os"
+)
+
+// runDebug implements `monkey debug file`, which runs file under the
+// debugger with commands read from standard input.
+func runDebug(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey debug file")
+		return 2
+	}
+
+	src, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := debugger.Run(args[0], src, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, sourceError(args[0], src, err))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,304 @@
+package debugger
+
+import (
+	"errors"
+	"monkey-go/ast"
+	"monkey-go/evaluator"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reasons for a pause, as passed to Controller.Paused. They are the
+// reasons of the stopped event of the Debug Adapter Protocol.
+const (
+	ReasonEntry      = "entry"
+	ReasonStep       = "step"
+	ReasonBreakpoint = "breakpoint"
+	ReasonPause      = "pause"
+)
+
+// ErrStopped is the result of a program stopped by the debugger.
+var ErrStopped = &object.Error{Message: "program stopped by the debugger"}
+
+// mode says when the program pauses next, apart from breakpoints.
+type mode int
+
+const (
+	modeContinue mode = iota // at a breakpoint only
+	modeStep                 // at the next statement
+	modeNext                 // at the next statement of the current call or a caller
+	modeOut                  // at the next statement of a caller
+)
+
+// Frame is a call being run. The outermost frame is the program itself.
+type Frame struct {
+	Name string
+	Env  *object.Environment // the scope of the statement being run
+	Line int                 // the line of the statement being run
+
+	last int // the line of the previous statement of this call, 0 before the first
+}
+
+// Controller runs a program and decides where it pauses. It implements
+// evaluator.Interrupter.
+//
+// Paused runs on the goroutine of the program. While it runs, the program
+// does not, and the methods reading its state, Frames and Evaluate, may be
+// used from any goroutine. Breakpoints can be changed and a pause requested
+// at any time.
+type Controller struct {
+	// Paused is called when the program pauses before the statement at
+	// the line of the innermost frame. The program resumes when Paused
+	// returns, unless it returns false, which stops the program.
+	Paused func(reason string) bool
+
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	mode        mode
+	depth       int  // the number of frames when Next or Out was called
+	pause       bool // pause at the next statement
+	stop        bool // stop the program at the next statement
+
+	started    bool
+	stack      []*Frame
+	evaluating bool // set while Evaluate runs code
+}
+
+// NewController returns a controller that pauses before the first
+// statement.
+func NewController() *Controller {
+	return &Controller{breakpoints: map[int]bool{}, mode: modeStep}
+}
+
+// Run evaluates program in env under the controller and returns its result,
+// ErrStopped if it was stopped. Only one program can run at a time.
+func (c *Controller) Run(program ast.Node, env *object.Environment) object.Object {
+	c.stack = []*Frame{{Name: "<program>", Env: env}}
+
+	old := evaluator.SetHook(c)
+	defer evaluator.SetHook(old)
+	return evaluator.Eval(program, env)
+}
+
+func (c *Controller) Statement(stmt ast.Statement, env *object.Environment) *object.Error {
+	if c.evaluating {
+		return nil
+	}
+
+	line := ast.Start(stmt).Line
+	top := c.stack[len(c.stack)-1]
+	top.Env, top.Line = env, line
+
+	previous := top.last
+	top.last = line
+
+	c.mu.Lock()
+	reason := ""
+	switch {
+	case c.stop:
+		c.mu.Unlock()
+		return ErrStopped
+	// several statements on a line stop once per call; every call, also
+	// a recursive one, stops again
+	case c.breakpoints[line] && line != previous:
+		reason = ReasonBreakpoint
+	case c.pause:
+		reason = ReasonPause
+	case c.mode == modeStep && !c.started:
+		reason = ReasonEntry
+	case c.mode == modeStep,
+		c.mode == modeNext && len(c.stack) <= c.depth,
+		c.mode == modeOut && len(c.stack) < c.depth:
+		reason = ReasonStep
+	}
+	c.started = true
+	if reason != "" {
+		c.pause = false
+		c.mode = modeContinue
+	}
+	c.mu.Unlock()
+
+	if reason == "" {
+		return nil
+	}
+	if c.Paused != nil && !c.Paused(reason) {
+		return ErrStopped
+	}
+	return nil
+}
+
+// Interrupt stops or pauses the program in code run by eval, which is shown
+// at the statement of the program running it. Breakpoints and steps do not
+// apply to that code.
+func (c *Controller) Interrupt() *object.Error {
+	if c.evaluating {
+		return nil
+	}
+
+	c.mu.Lock()
+	stop, pause := c.stop, c.pause
+	if pause {
+		c.pause = false
+		c.mode = modeContinue
+	}
+	c.mu.Unlock()
+
+	if stop {
+		return ErrStopped
+	}
+	if pause && c.Paused != nil && !c.Paused(ReasonPause) {
+		return ErrStopped
+	}
+	return nil
+}
+
+func (c *Controller) Enter(call *ast.CallExpression, fn *object.Function, env *object.Environment) {
+	if c.evaluating {
+		return
+	}
+
+	name := "function literal"
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		name = ident.Value
+	}
+	c.stack = append(c.stack, &Frame{Name: name, Env: env, Line: ast.Start(call).Line})
+}
+
+func (c *Controller) Leave(call *ast.CallExpression, fn *object.Function, result object.Object) {
+	if c.evaluating {
+		return
+	}
+	c.stack = c.stack[:len(c.stack)-1]
+}
+
+// Continue resumes the program until a breakpoint.
+func (c *Controller) Continue() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mode = modeContinue
+}
+
+// Step pauses at the next statement, stepping into calls.
+func (c *Controller) Step() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mode = modeStep
+}
+
+// Next pauses at the next statement of the current call or of a caller,
+// stepping over calls.
+func (c *Controller) Next() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mode, c.depth = modeNext, len(c.stack)
+}
+
+// Out pauses at the next statement of a caller. It reports false, and
+// changes nothing, in the outermost frame.
+func (c *Controller) Out() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.stack) <= 1 {
+		return false
+	}
+	c.mode, c.depth = modeOut, len(c.stack)
+	return true
+}
+
+// Pause pauses a running program at its next statement.
+func (c *Controller) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pause = true
+}
+
+// Stop stops the program at its next statement. A paused program is not
+// resumed by Stop; Paused must return as well.
+func (c *Controller) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop = true
+}
+
+func (c *Controller) SetBreakpoint(line int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.breakpoints[line] = true
+}
+
+// ClearBreakpoint removes the breakpoint at line and reports whether there
+// was one.
+func (c *Controller) ClearBreakpoint(line int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ok := c.breakpoints[line]
+	delete(c.breakpoints, line)
+	return ok
+}
+
+// ClearBreakpoints removes all breakpoints.
+func (c *Controller) ClearBreakpoints() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.breakpoints = map[int]bool{}
+}
+
+// Breakpoints returns the lines with a breakpoint, sorted.
+func (c *Controller) Breakpoints() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lines := make([]int, 0, len(c.breakpoints))
+	for line := range c.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Frames returns the calls being run, innermost first.
+func (c *Controller) Frames() []Frame {
+	frames := make([]Frame, len(c.stack))
+	for i, f := range c.stack {
+		frames[len(c.stack)-1-i] = *f
+	}
+	return frames
+}
+
+// Evaluate runs src in the scope of frame i of Frames. Bindings it declares
+// stay in that scope. Breakpoints do not apply to it. An error is returned
+// if src does not parse.
+func (c *Controller) Evaluate(src string, i int) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+	frames := c.Frames()
+	if i < 0 || i >= len(frames) {
+		return nil, errors.New("no such frame")
+	}
+
+	c.evaluating = true
+	defer func() { c.evaluating = false }()
+	return evaluator.Eval(program, frames[i].Env), nil
+}
+
+// StatementLines returns the lines on which a statement starts, where a
+// breakpoint can pause the program.
+func StatementLines(program *ast.Program) map[int]bool {
+	lines := map[int]bool{}
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if _, ok := node.(*ast.BlockStatement); ok {
+			return node
+		}
+		if stmt, ok := node.(ast.Statement); ok {
+			lines[ast.Start(stmt).Line] = true
+		}
+		return node
+	})
+	return lines
+}
@@ -0,0 +1,282 @@
+// Package debugger runs a Monkey program under the control of a debugger:
+// breakpoints by line, stepping into, over and out of calls, inspecting
+// variables and evaluating expressions in the paused frame. Controller does
+// the bookkeeping; Run drives it with commands read from an input stream
+// and is used by `monkey debug`.
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"monkey-go/evaluator"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"strings"
+)
+
+// session is a program run under the command line debugger.
+type session struct {
+	name     string
+	lines    []string
+	commands *bufio.Scanner
+	out      io.Writer
+	last     string
+
+	c *Controller
+}
+
+// Run runs the program src, called name in messages, under the debugger.
+// It pauses before the first statement. Commands are read from commands
+// and all output goes to out. An error, a *parser.SyntaxError, is returned
+// only if src does not parse.
+func Run(name string, src []byte, commands io.Reader, out io.Writer) error {
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return &parser.SyntaxError{Errors: p.ErrorList()}
+	}
+
+	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, macroEnv)
+	if err != nil {
+		fmt.Fprintln(out, err.Inspect())
+		return nil
+	}
+
+	d := &session{
+		name:     name,
+		lines:    strings.Split(string(src), "\n"),
+		commands: bufio.NewScanner(commands),
+		out:      out,
+		c:        NewController(),
+	}
+	d.c.Paused = d.paused
+
+	result := d.c.Run(expanded, env)
+	switch {
+	case result == ErrStopped:
+		fmt.Fprintln(out, "quit")
+	case result == nil:
+		fmt.Fprintln(out, "program finished")
+	default:
+		fmt.Fprintf(out, "program finished: %s\n", result.Inspect())
+	}
+	return nil
+}
+
+func (d *session) paused(reason string) bool {
+	line := d.c.Frames()[0].Line
+	if reason == ReasonBreakpoint {
+		fmt.Fprintf(d.out, "breakpoint at line %d\n", line)
+	}
+	fmt.Fprintf(d.out, "-> %s:%d  %s\n", d.name, line, d.source(line))
+	return d.prompt()
+}
+
+func (d *session) source(line int) string {
+	if line < 1 || line > len(d.lines) {
+		return ""
+	}
+	return strings.TrimSpace(d.lines[line-1])
+}
+
+const help = `commands:
+  break LINE, b LINE    pause when LINE is reached
+  clear LINE            remove the breakpoint at LINE
+  breakpoints           list the breakpoints
+  continue, c           run until a breakpoint
+  step, s               run the next statement, stepping into calls
+  next, n               run the next statement, stepping over calls
+  out, o                run until the current call returns
+  print EXPR, p EXPR    evaluate EXPR in the current scope
+  locals                list the variables of the current scope
+  vars                  list the variables of every enclosing scope
+  backtrace, bt         list the calls being run
+  list, l               show the source around the current line
+  quit, q               stop the program
+An empty line repeats the previous command.`
+
+// prompt reads commands until one resumes the program. It returns false
+// to stop the program.
+func (d *session) prompt() bool {
+	for {
+		fmt.Fprint(d.out, "(debug) ")
+		if !d.commands.Scan() {
+			fmt.Fprintln(d.out)
+			return false
+		}
+
+		line := strings.TrimSpace(d.commands.Text())
+		if line == "" {
+			line = d.last
+		}
+		d.last = line
+		cmd, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+
+		switch cmd {
+		case "":
+
+		case "continue", "c":
+			d.c.Continue()
+			return true
+
+		case "step", "s":
+			d.c.Step()
+			return true
+
+		case "next", "n":
+			d.c.Next()
+			return true
+
+		case "out", "o":
+			if !d.c.Out() {
+				fmt.Fprintln(d.out, "not in a function call")
+				continue
+			}
+			return true
+
+		case "break", "b":
+			if line, ok := d.lineArg(arg); ok {
+				d.c.SetBreakpoint(line)
+				fmt.Fprintf(d.out, "breakpoint set at line %d\n", line)
+			}
+
+		case "clear":
+			if line, ok := d.lineArg(arg); ok {
+				if !d.c.ClearBreakpoint(line) {
+					fmt.Fprintf(d.out, "no breakpoint at line %d\n", line)
+					continue
+				}
+				fmt.Fprintf(d.out, "breakpoint at line %d cleared\n", line)
+			}
+
+		case "breakpoints":
+			d.listBreakpoints()
+
+		case "print", "p":
+			d.print(arg)
+
+		case "locals":
+			d.variables(d.c.Frames()[0].Env, false)
+
+		case "vars":
+			d.variables(d.c.Frames()[0].Env, true)
+
+		case "backtrace", "bt":
+			for i, f := range d.c.Frames() {
+				fmt.Fprintf(d.out, "#%d %s at line %d\n", i, f.Name, f.Line)
+			}
+
+		case "list", "l":
+			d.list(d.c.Frames()[0].Line)
+
+		case "help", "h":
+			fmt.Fprintln(d.out, help)
+
+		case "quit", "q":
+			return false
+
+		default:
+			fmt.Fprintf(d.out, "unknown command %q; type help for a list\n", cmd)
+		}
+	}
+}
+
+func (d *session) lineArg(arg string) (int, bool) {
+	var line int
+	if _, err := fmt.Sscan(arg, &line); err != nil || line < 1 || line > len(d.lines) {
+		fmt.Fprintf(d.out, "invalid line %q\n", arg)
+		return 0, false
+	}
+	return line, true
+}
+
+func (d *session) listBreakpoints() {
+	lines := d.c.Breakpoints()
+	if len(lines) == 0 {
+		fmt.Fprintln(d.out, "no breakpoints")
+		return
+	}
+	for _, line := range lines {
+		fmt.Fprintf(d.out, "line %d  %s\n", line, d.source(line))
+	}
+}
+
+// print evaluates src in the scope of the paused statement.
+func (d *session) print(src string) {
+	result, err := d.c.Evaluate(src, 0)
+	if err != nil {
+		fmt.Fprintln(d.out, err)
+		return
+	}
+	if result != nil {
+		fmt.Fprintln(d.out, Inspect(result))
+	}
+}
+
+// Inspect is object.Object.Inspect with functions shortened to their
+// parameters.
+func Inspect(obj object.Object) string {
+	fn, ok := obj.(*object.Function)
+	if !ok {
+		return obj.Inspect()
+	}
+	params := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = param.String()
+	}
+	return "fn(" + strings.Join(params, ", ") + ") { ... }"
+}
+
+// variables prints the bindings of env, and of the scopes around it if all
+// is set. Builtins are not bindings and are not listed.
+func (d *session) variables(env *object.Environment, all bool) {
+	for depth := 0; env != nil; depth++ {
+		if all {
+			fmt.Fprintf(d.out, "scope %d:\n", depth)
+		}
+		for _, name := range env.Names() {
+			value, _ := env.Get(name)
+			keyword := "let"
+			if env.IsConst(name) {
+				keyword = "const"
+			}
+			fmt.Fprintf(d.out, "  %s %s = %s\n", keyword, name, Inspect(value))
+		}
+		if !all {
+			return
+		}
+		env = env.Outer()
+	}
+}
+
+// list shows the lines around line.
+func (d *session) list(line int) {
+	from, to := line-3, line+3
+	if from < 1 {
+		from = 1
+	}
+	if to > len(d.lines) {
+		to = len(d.lines)
+	}
+
+	breakpoints := map[int]bool{}
+	for _, n := range d.c.Breakpoints() {
+		breakpoints[n] = true
+	}
+	for n := from; n <= to; n++ {
+		marker := "  "
+		switch {
+		case n == line:
+			marker = "->"
+		case breakpoints[n]:
+			marker = "* "
+		}
+		fmt.Fprintf(d.out, "%s %3d  %s\n", marker, n, d.lines[n-1])
+	}
+}
@@ -0,0 +1,233 @@
+package debugger
+
+import (
+	"bytes"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"strings"
+	"testing"
+	"time"
+)
+
+const program = `let add = fn(a, b) {
+    let sum = a + b;
+    sum
+};
+let x = 1;
+let y = add(x, 2);
+const z = y * 2;
+z`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands string
+		expected string
+	}{
+		{
+			"breakpoint, backtrace and variables",
+			"b 2\nc\nbt\nlocals\np a + b * 10\nvars\nc\n",
+			`-> test.monkey:1  let add = fn(a, b) {
+(debug) breakpoint set at line 2
+(debug) breakpoint at line 2
+-> test.monkey:2  let sum = a + b;
+(debug) #0 add at line 2
+#1 <program> at line 6
+(debug)   let a = 1
+  let b = 2
+(debug) 21
+(debug) scope 0:
+  let a = 1
+  let b = 2
+scope 1:
+  let add = fn(a, b) { ... }
+  let x = 1
+(debug) program finished: 6
+`,
+		},
+		{
+			"next steps over calls",
+			"n\nn\nn\np y\n\n\n",
+			`-> test.monkey:1  let add = fn(a, b) {
+(debug) -> test.monkey:5  let x = 1;
+(debug) -> test.monkey:6  let y = add(x, 2);
+(debug) -> test.monkey:7  const z = y * 2;
+(debug) 3
+(debug) 3
+(debug) 3
+(debug) 
+quit
+`,
+		},
+		{
+			"step into and out of a call",
+			"n\nn\ns\ns\nout\nlocals\nq\n",
+			`-> test.monkey:1  let add = fn(a, b) {
+(debug) -> test.monkey:5  let x = 1;
+(debug) -> test.monkey:6  let y = add(x, 2);
+(debug) -> test.monkey:2  let sum = a + b;
+(debug) -> test.monkey:3  sum
+(debug) -> test.monkey:7  const z = y * 2;
+(debug)   let add = fn(a, b) { ... }
+  let x = 1
+  let y = 3
+(debug) quit
+`,
+		},
+		{
+			"breakpoints and list",
+			"b 7\nb 3\nclear 3\nclear 3\nb 99\nbreakpoints\nl\nout\nfoo\nc\n",
+			`-> test.monkey:1  let add = fn(a, b) {
+(debug) breakpoint set at line 7
+(debug) breakpoint set at line 3
+(debug) breakpoint at line 3 cleared
+(debug) no breakpoint at line 3
+(debug) invalid line "99"
+(debug) line 7  const z = y * 2;
+(debug) ->   1  let add = fn(a, b) {
+     2      let sum = a + b;
+     3      sum
+     4  };
+(debug) not in a function call
+(debug) unknown command "foo"; type help for a list
+(debug) breakpoint at line 7
+-> test.monkey:7  const z = y * 2;
+(debug) 
+quit
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := Run("test.monkey", []byte(program), strings.NewReader(tt.commands), &out)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s: wrong output.\nwant=%q\ngot=%q", tt.name, tt.expected, out.String())
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	var out bytes.Buffer
+	err := Run("test.monkey", []byte("let = 1;"), strings.NewReader(""), &out)
+	if err == nil || !strings.HasPrefix(err.Error(), "expected next token to be IDENT, got = instead.") {
+		t.Errorf("wrong parse error. got=%v", err)
+	}
+
+	out.Reset()
+	err = Run("test.monkey", []byte("let x = 1;\nx + true"), strings.NewReader("c\n"), &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "-> test.monkey:1  let x = 1;\n(debug) program finished: ERROR: type mismatch: INTEGER + BOOLEAN\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestBreakpointInRepeatedCalls(t *testing.T) {
+	const calls = `let f = fn(n) {
+  n == 0 ? 0 : f(n - 1) + n
+};
+let g = fn(x) {
+  x
+};
+f(2);
+g(1); g(2)`
+
+	var out bytes.Buffer
+	commands := "b 2\nb 5\nc\np n\nc\np n\nc\np n\nc\np x\nc\np x\nc\n"
+	if err := Run("test.monkey", []byte(calls), strings.NewReader(commands), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `-> test.monkey:1  let f = fn(n) {
+(debug) breakpoint set at line 2
+(debug) breakpoint set at line 5
+(debug) breakpoint at line 2
+-> test.monkey:2  n == 0 ? 0 : f(n - 1) + n
+(debug) 2
+(debug) breakpoint at line 2
+-> test.monkey:2  n == 0 ? 0 : f(n - 1) + n
+(debug) 1
+(debug) breakpoint at line 2
+-> test.monkey:2  n == 0 ? 0 : f(n - 1) + n
+(debug) 0
+(debug) breakpoint at line 5
+-> test.monkey:5  x
+(debug) 1
+(debug) breakpoint at line 5
+-> test.monkey:5  x
+(debug) 2
+(debug) program finished: 2
+`
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestBreakpointOnceForALine(t *testing.T) {
+	var out bytes.Buffer
+	src := "let a = 1;\nlet c = a; let d = c;\nd"
+	if err := Run("test.monkey", []byte(src), strings.NewReader("b 2\nc\nc\n"), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `-> test.monkey:1  let a = 1;
+(debug) breakpoint set at line 2
+(debug) breakpoint at line 2
+-> test.monkey:2  let c = a; let d = c;
+(debug) program finished: 1
+`
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestInterruptEval(t *testing.T) {
+	src := "let x = 1;\neval(\"let f = fn(n) { f(n + 1) }; f(0)\")"
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	for _, reason := range []string{ReasonPause, ""} {
+		c := NewController()
+		var frames []Frame
+		c.Paused = func(r string) bool {
+			switch {
+			case r == ReasonStep:
+				// at the call of eval, which spins until interrupted
+				c.Continue()
+				if reason == ReasonPause {
+					c.Pause()
+				} else {
+					c.Stop()
+				}
+				return true
+			case r == ReasonPause:
+				frames = c.Frames()
+				return false
+			}
+			c.Next()
+			return true
+		}
+
+		done := make(chan object.Object, 1)
+		go func() { done <- c.Run(program, object.NewEnvironment()) }()
+		select {
+		case result := <-done:
+			if result != ErrStopped {
+				t.Errorf("wrong result. want=%v, got=%v", ErrStopped, result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("program in eval not interrupted (reason %q)", reason)
+		}
+		if reason == ReasonPause && (len(frames) != 1 || frames[0].Line != 2) {
+			t.Errorf("not paused at the call of eval. got=%+v", frames)
+		}
+	}
+}
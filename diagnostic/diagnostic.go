@@ -0,0 +1,153 @@
+// Package diagnostic renders the errors found in Monkey source: the
+// message, the position, the line of source with the offending text
+// underlined and a hint, optionally in colour:
+//
+//	error: identifier not found: frist
+//	 --> hello.monkey:3:7
+//	  |
+//	3 | print(frist(xs));
+//	  |       ^^^^^
+//	  = help: did you mean `first`?
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Diagnostic is an error at a position of a source file.
+type Diagnostic struct {
+	Line, Column int // 1-based, the column counted in runes; 0 if unknown
+	Message      string
+	Notes        []string // more about the error, such as where it came from
+	Hint         string   // a suggested fix, or empty
+}
+
+// FromParser returns the diagnostics of parser errors.
+func FromParser(errs []*parser.Error) []Diagnostic {
+	diagnostics := make([]Diagnostic, len(errs))
+	for i, err := range errs {
+		diagnostics[i] = Diagnostic{Line: err.Line, Column: err.Column, Message: err.Message}
+	}
+	return diagnostics
+}
+
+// FromError returns the diagnostic of an evaluation error.
+func FromError(err *object.Error) Diagnostic {
+	return Diagnostic{Line: err.Line, Column: err.Column, Message: err.Message, Hint: err.Hint}
+}
+
+// ANSI escape sequences used when printing in colour.
+const (
+	bold  = "\x1b[1m"
+	red   = "\x1b[1;31m"
+	blue  = "\x1b[1;34m"
+	cyan  = "\x1b[1;36m"
+	reset = "\x1b[0m"
+)
+
+// A Printer prints diagnostics of the source file File, whose contents are
+// Source.
+type Printer struct {
+	File   string
+	Source string
+	Color  bool // use ANSI colours
+
+	// Offset is the number of lines the positions of the diagnostics count
+	// before the first line of Source, for a source parsed after others.
+	Offset int
+}
+
+// Print writes d to w. The source line is left out if d has no position
+// in the source.
+func (p *Printer) Print(w io.Writer, d Diagnostic) error {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s: %s\n", p.paint(red, "error"), p.paint(bold, d.Message))
+
+	lines := strings.Split(p.Source, "\n")
+	d.Line -= p.Offset
+	if d.Line < 1 || d.Line > len(lines) {
+		if p.File != "" {
+			fmt.Fprintf(&out, "%s %s\n", p.paint(blue, "-->"), p.File)
+		}
+		p.hint(&out, d, "")
+		_, err := io.WriteString(w, out.String())
+		return err
+	}
+
+	line := []rune(strings.TrimSuffix(lines[d.Line-1], "\r"))
+	number := fmt.Sprint(d.Line)
+	pad := strings.Repeat(" ", len(number))
+	fmt.Fprintf(&out, "%s%s %s:%d:%d\n", pad, p.paint(blue, "-->"), p.File, d.Line, d.Column)
+	fmt.Fprintf(&out, "%s %s\n", pad, p.paint(blue, "|"))
+	fmt.Fprintf(&out, "%s %s %s\n", p.paint(blue, number), p.paint(blue, "|"), string(line))
+
+	// the underline keeps the tabs of the line so that it stays aligned
+	column := d.Column
+	if column < 1 {
+		column = 1
+	}
+	if column > len(line)+1 {
+		column = len(line) + 1
+	}
+	var indent strings.Builder
+	for _, r := range line[:column-1] {
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	carets := strings.Repeat("^", wordLength(line[column-1:]))
+	fmt.Fprintf(&out, "%s %s %s%s\n", pad, p.paint(blue, "|"), indent.String(), p.paint(red, carets))
+	p.hint(&out, d, pad)
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// hint writes the notes and the hint of d below it.
+func (p *Printer) hint(out *strings.Builder, d Diagnostic, pad string) {
+	for _, note := range d.Notes {
+		fmt.Fprintf(out, "%s %s %s: %s\n", pad, p.paint(blue, "="), p.paint(cyan, "note"), note)
+	}
+	if d.Hint != "" {
+		fmt.Fprintf(out, "%s %s %s: %s\n", pad, p.paint(blue, "="), p.paint(cyan, "help"), d.Hint)
+	}
+}
+
+func (p *Printer) paint(color, s string) string {
+	if !p.Color {
+		return s
+	}
+	return color + s + reset
+}
+
+// wordLength returns the length of the identifier or number text starts
+// with, or 1.
+func wordLength(text []rune) int {
+	n := 0
+	for n < len(text) && (text[n] == '_' || unicode.IsLetter(text[n]) || unicode.IsDigit(text[n])) {
+		n++
+	}
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+// IsTerminal reports whether w is a terminal that colours can be used on:
+// it is not if the NO_COLOR environment variable is set.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
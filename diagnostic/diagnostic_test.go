@@ -0,0 +1,108 @@
+package diagnostic
+
+import (
+	"bytes"
+	"monkey-go/lexer"
+	"monkey-go/parser"
+	"reflect"
+	"testing"
+)
+
+const source = `let length = 3;
+let f = fn(x) {
+	x + lenght
+};
+f(1)`
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		d        Diagnostic
+		expected string
+	}{
+		{
+			Diagnostic{Line: 3, Column: 6, Message: "identifier not found: lenght", Hint: "did you mean `length`?"},
+			`error: identifier not found: lenght
+ --> test.monkey:3:6
+  |
+3 | 	x + lenght
+  | 	    ^^^^^^
+  = help: did you mean ` + "`length`?" + `
+`,
+		},
+		{
+			Diagnostic{Line: 5, Column: 2, Message: "unexpected ("},
+			`error: unexpected (
+ --> test.monkey:5:2
+  |
+5 | f(1)
+  |  ^
+`,
+		},
+		{
+			// past the end of the line, as errors at the end of the input are
+			Diagnostic{Line: 5, Column: 9, Message: "expected }"},
+			`error: expected }
+ --> test.monkey:5:9
+  |
+5 | f(1)
+  |     ^
+`,
+		},
+		{
+			Diagnostic{Line: 5, Column: 1, Message: "call failed", Notes: []string{"f is called here", "with 1"}, Hint: "fix f"},
+			`error: call failed
+ --> test.monkey:5:1
+  |
+5 | f(1)
+  | ^
+  = note: f is called here
+  = note: with 1
+  = help: fix f
+`,
+		},
+		{
+			Diagnostic{Message: "stopped", Hint: "try again"},
+			`error: stopped
+--> test.monkey
+ = help: try again
+`,
+		},
+	}
+
+	printer := &Printer{File: "test.monkey", Source: source}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := printer.Print(&out, tt.d); err != nil {
+			t.Fatalf("Print: %v", err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("wrong output.\nwant=\n%s\ngot=\n%s", tt.expected, out.String())
+		}
+	}
+}
+
+func TestPrintColor(t *testing.T) {
+	var out bytes.Buffer
+	printer := &Printer{File: "test.monkey", Source: source, Color: true}
+	printer.Print(&out, Diagnostic{Line: 5, Column: 1, Message: "oops"})
+
+	expected := "\x1b[1;31merror\x1b[0m: \x1b[1moops\x1b[0m\n" +
+		" \x1b[1;34m-->\x1b[0m test.monkey:5:1\n" +
+		"  \x1b[1;34m|\x1b[0m\n" +
+		"\x1b[1;34m5\x1b[0m \x1b[1;34m|\x1b[0m f(1)\n" +
+		"  \x1b[1;34m|\x1b[0m \x1b[1;31m^\x1b[0m\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestFromParser(t *testing.T) {
+	p := parser.New(lexer.New("let x = 1;\nlet = 2;"))
+	p.ParseProgram()
+
+	diagnostics := FromParser(p.ErrorList())
+	expected := Diagnostic{Line: 2, Column: 5, Message: "expected next token to be IDENT, got = instead."}
+	if len(diagnostics) != 1 || !reflect.DeepEqual(diagnostics[0], expected) {
+		t.Errorf("wrong diagnostics. want=%+v, got=%+v", expected, diagnostics)
+	}
+}
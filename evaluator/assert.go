@@ -0,0 +1,173 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey-go/object"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// assertError calls a function, so the assertions are registered in init.
+func init() {
+	register("assert", &object.Builtin{
+		Doc: "assert(cond, msg) returns null if cond is truthy and fails with msg, which is optional, otherwise.",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+			msg, err := assertMessage("assert", args[1:])
+			if err != nil {
+				return err
+			}
+
+			if !isTruthy(args[0]) {
+				return newError("assertion failed%s", msg)
+			}
+			return NULL
+		},
+	})
+	register("assertEq", &object.Builtin{
+		Doc: "assertEq(got, want, msg) returns null if got and want are equal, comparing arrays and hashes by their contents, and fails with msg, which is optional, otherwise.",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3",
+					len(args))
+			}
+			msg, err := assertMessage("assertEq", args[2:])
+			if err != nil {
+				return err
+			}
+
+			got, want := args[0], args[1]
+			if object.Equal(got, want) {
+				return NULL
+			}
+			var out strings.Builder
+			fmt.Fprintf(&out, "assertEq failed%s\n  got:  %s\n  want: %s", msg, quoted(got), quoted(want))
+			if path, diff := difference(got, want, ""); path != "" {
+				fmt.Fprintf(&out, "\n  at %s: %s", path, diff)
+			}
+			return newError("%s", out.String())
+		},
+	})
+	register("assertError", &object.Builtin{
+		Doc: "assertError(fn, text) calls fn without arguments and returns null if it fails with an error containing text, which is optional, and fails otherwise.",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2",
+					len(args))
+			}
+			switch args[0].(type) {
+			case *object.Function, *object.Builtin:
+			default:
+				return newError("argument to `assertError` must be FUNCTION, got %s",
+					args[0].Type())
+			}
+			text := ""
+			if len(args) == 2 {
+				s, ok := args[1].(*object.String)
+				if !ok {
+					return newError("argument to `assertError` must be STRING, got %s",
+						args[1].Type())
+				}
+				text = s.Value
+			}
+
+			result := Call(args[0])
+			errObj, ok := result.(*object.Error)
+			if !ok {
+				return newError("assertError failed: got=%s, want an error", result.Inspect())
+			}
+			if !strings.Contains(errObj.Message, text) {
+				return newError("assertError failed: error %q does not contain %q", errObj.Message, text)
+			}
+			return NULL
+		},
+	})
+}
+
+// assertMessage returns the optional message argument of the assertion
+// builtin name, formatted to follow "failed".
+func assertMessage(name string, args []object.Object) (string, *object.Error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	s, ok := args[0].(*object.String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	return ": " + s.Value, nil
+}
+
+// difference locates the first difference between the unequal objects got
+// and want nested in arrays or hashes and describes it. It returns an
+// empty path when they differ at the top, at path, already.
+func difference(got, want object.Object, path string) (string, string) {
+	switch want := want.(type) {
+	case *object.Array:
+		got, ok := got.(*object.Array)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(got.Elements) && i < len(want.Elements); i++ {
+			if !object.Equal(got.Elements[i], want.Elements[i]) {
+				return difference(got.Elements[i], want.Elements[i], fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+		if path == "" {
+			return "", ""
+		}
+		return path, fmt.Sprintf("got %d elements, want %d", len(got.Elements), len(want.Elements))
+
+	case *object.HashMap:
+		got, ok := got.(*object.HashMap)
+		if !ok {
+			break
+		}
+		for _, key := range sortedKeys(want) {
+			pw := want.Pairs[key]
+			elem := fmt.Sprintf("%s[%s]", path, quoted(pw.Key))
+			pg, ok := got.Pairs[key]
+			if !ok {
+				return elem, "missing"
+			}
+			if !object.Equal(pg.Value, pw.Value) {
+				return difference(pg.Value, pw.Value, elem)
+			}
+		}
+		for _, key := range sortedKeys(got) {
+			if _, ok := want.Pairs[key]; !ok {
+				return fmt.Sprintf("%s[%s]", path, quoted(got.Pairs[key].Key)), "unexpected"
+			}
+		}
+	}
+
+	if path == "" {
+		return "", ""
+	}
+	return path, fmt.Sprintf("got %s, want %s", quoted(got), quoted(want))
+}
+
+// sortedKeys returns the keys of h in the order of their inspected keys,
+// so that differences are reported deterministically.
+func sortedKeys(h *object.HashMap) []object.HashKey {
+	keys := make([]object.HashKey, 0, len(h.Pairs))
+	for key := range h.Pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return h.Pairs[keys[i]].Key.Inspect() < h.Pairs[keys[j]].Key.Inspect()
+	})
+	return keys
+}
+
+// quoted inspects obj, quoting strings so that they are told apart from
+// other values.
+func quoted(obj object.Object) string {
+	if s, ok := obj.(*object.String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,56 @@
+package evaluator
+
+import (
+	"monkey-go/object"
+	"testing"
+)
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the error message, "" if the assertion passes
+	}{
+		{`assert(1 < 2)`, ""},
+		{`assert(1 > 2)`, "assertion failed"},
+		{`assert(false, "one is small")`, "assertion failed: one is small"},
+		{`assert(false, 1)`, "argument to `assert` must be STRING, got INTEGER"},
+		{`assert()`, "wrong number of arguments. got=0, want=1 or 2"},
+		{`assertEq([1, {"a": [2]}], [1, {"a": [2]}])`, ""},
+		{`assertEq(1, 2)`, "assertEq failed\n  got:  1\n  want: 2"},
+		{`assertEq("1", 1, "types")`, "assertEq failed: types\n  got:  \"1\"\n  want: 1"},
+		{`assertEq([1, 2, 4], [1, 2, 3])`,
+			"assertEq failed\n  got:  [1, 2, 4]\n  want: [1, 2, 3]\n  at [2]: got 4, want 3"},
+		{`assertEq([[1], [2, 3]], [[1], [2]])`,
+			"assertEq failed\n  got:  [[1], [2, 3]]\n  want: [[1], [2]]\n  at [1]: got 2 elements, want 1"},
+		{`assertEq({"a": {"b": "x"}}, {"a": {"b": "y"}})`,
+			"assertEq failed\n  got:  {a: {b: x}}\n  want: {a: {b: y}}\n  at [\"a\"][\"b\"]: got \"x\", want \"y\""},
+		{`assertEq({"a": 1}, {"b": 1})`,
+			"assertEq failed\n  got:  {a: 1}\n  want: {b: 1}\n  at [\"b\"]: missing"},
+		{`assertEq([1], [])`, "assertEq failed\n  got:  [1]\n  want: []"},
+		{`assertError(fn() { 1 + true })`, ""},
+		{`assertError(fn() { 1 + true }, "type mismatch")`, ""},
+		{`assertError(fn() { 1 + true }, "unknown")`,
+			"assertError failed: error \"type mismatch: INTEGER + BOOLEAN\" does not contain \"unknown\""},
+		{`assertError(fn() { 1 })`, "assertError failed: got=1, want an error"},
+		{`assertError(len)`, ""},
+		{`assertError(1)`, "argument to `assertError` must be FUNCTION, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if tt.expected == "" {
+			if evaluated != NULL {
+				t.Errorf("%s: expected null. got=%s", tt.input, evaluated.Inspect())
+			}
+			continue
+		}
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("%s: wrong error message.\nwant=%q\ngot=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
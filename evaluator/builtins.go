@@ -2,11 +2,19 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
 	"monkey-go/object"
+	"os"
+	"sort"
 )
 
+// Stdout is where print writes. Tools that use standard output themselves,
+// such as the debug adapter, redirect it.
+var Stdout io.Writer = os.Stdout
+
 var builtins = map[string]*object.Builtin{
 	"len": {
+		Doc: "len(x) returns the number of characters in a string or elements in an array.",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1",
@@ -24,6 +32,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"first": &object.Builtin{
+		Doc: "first(array) returns the first element of array, or null if it is empty.",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1",
@@ -43,6 +52,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"last": &object.Builtin{
+		Doc: "last(array) returns the last element of array, or null if it is empty.",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1",
@@ -63,6 +73,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"rest": &object.Builtin{
+		Doc: "rest(array) returns a new array without the first element of array, or null if it is empty.",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1",
@@ -85,6 +96,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"push": &object.Builtin{
+		Doc: "push(array, value) returns a new array with value appended to array.",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
 				return newError("wrong number of arguments. got=%d, want=2",
@@ -106,12 +118,72 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"print": &object.Builtin{
+		Doc: "print(values...) prints each value on a line of its own and returns null.",
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				fmt.Fprintln(Stdout, arg.Inspect())
 			}
 
 			return NULL
 		},
 	},
 }
+
+func init() {
+	for name, b := range builtins {
+		b.Name = name
+	}
+}
+
+// register adds b to the builtin functions as name. Builtins that call
+// functions are registered from init functions: listing them in builtins
+// would make the table depend on Eval, which depends on the table.
+func register(name string, b *object.Builtin) {
+	b.Name = name
+	builtins[name] = b
+}
+
+// IsBuiltin reports whether name refers to a built-in function, or to one
+// of the special forms, when it is not bound in the environment.
+func IsBuiltin(name string) bool {
+	if _, ok := specialFormDocs[name]; ok {
+		return true
+	}
+	_, ok := builtins[name]
+	return ok
+}
+
+// specialFormDocs documents quote and unquote, and globals, locals and
+// eval, which are handled by Eval rather than by a builtin function.
+var specialFormDocs = map[string]string{
+	"quote":   "quote(expr) returns expr unevaluated as a QUOTE value.",
+	"unquote": "unquote(expr) evaluates expr inside a quote and splices the result into it.",
+	"globals": "globals() returns a hash of the names bound at the top level to their values.",
+	"locals":  "locals() returns a hash of the names bound in the innermost function call and the blocks in it, or at the top level, to their values.",
+	"eval":    "eval(src, env?) runs the program src in the current scope, or with only the bindings of the hash env, and returns its value.",
+}
+
+// BuiltinNames returns the names IsBuiltin reports, sorted.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins)+len(specialFormDocs))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range specialFormDocs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinDoc returns the documentation of the builtin function or special
+// form name.
+func BuiltinDoc(name string) (string, bool) {
+	if doc, ok := specialFormDocs[name]; ok {
+		return doc, true
+	}
+	if b, ok := builtins[name]; ok {
+		return b.Doc, true
+	}
+	return "", false
+}
@@ -0,0 +1,83 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+)
+
+// evalEvalCall evaluates a call of eval(src, env?). src is run as a
+// program in the environment of the caller, or, if env is given, in a new
+// environment holding only the bindings of the hash env, so that it can
+// neither read nor change the variables of the caller. The positions of
+// the code are those of src, which would be mistaken for positions of the
+// program, so the Hook is not told about the code nor the functions it
+// defines. An Interrupter can still stop it.
+func evalEvalCall(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 && len(call.Arguments) != 2 {
+		return newError("wrong number of arguments to eval. got=%d, want=1 or 2", len(call.Arguments))
+	}
+	args := evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	src, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to `eval` must be STRING, got %s", typeOf(args[0]))
+	}
+	if len(args) == 2 {
+		bindings, ok := args[1].(*object.HashMap)
+		if !ok {
+			return newError("environment of `eval` must be HASHMAP, got %s", typeOf(args[1]))
+		}
+		env = object.NewEnvironment()
+		for _, pair := range bindings.Pairs {
+			name, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError("names in the environment of `eval` must be STRING, got %s", typeOf(pair.Key))
+			}
+			env.Set(name.Value, pair.Value)
+		}
+	}
+
+	p := parser.New(lexer.New(src.Value))
+	program := p.ParseProgram()
+	if errs := p.ErrorList(); len(errs) > 0 {
+		return newError("eval: %s", errs[0])
+	}
+
+	defer func(outer bool) { inEval = outer }(inEval)
+	inEval = true
+
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded, err := ExpandMacros(program, macroEnv)
+	if err != nil {
+		return evalError(err)
+	}
+
+	result := Eval(expanded, env)
+	switch result := result.(type) {
+	case nil:
+		return NULL
+	case *object.Error:
+		if result == stopped {
+			return result
+		}
+		return evalError(result)
+	}
+	return result
+}
+
+// evalError returns err, raised by code run by eval, located at the call of
+// eval: its position in the source string is moved into the message.
+func evalError(err *object.Error) *object.Error {
+	message := "eval: " + err.Message
+	if err.Line != 0 {
+		message = fmt.Sprintf("eval: %d:%d: %s", err.Line, err.Column, err.Message)
+	}
+	return &object.Error{Message: message, Hint: err.Hint}
+}
@@ -0,0 +1,52 @@
+package evaluator
+
+import (
+	"monkey-go/object"
+	"testing"
+)
+
+func TestEvalBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the inspected result
+	}{
+		{`eval("1 + 2")`, "3"},
+		{`eval("")`, "null"},
+		{`let x = 2; eval("x * 21")`, "42"},
+		{`eval("let y = 3;"); y`, "3"},
+		{`let f = fn(a) { eval("a + 1") }; f(5)`, "6"},
+		{`eval("return 1; 2")`, "1"},
+		{`eval("let double = macro(x) { quote(unquote(x) * 2) }; double(4)")`, "8"},
+		{`eval("z + 1", {"z": 1})`, "2"},
+		{`let x = 1; eval("x", {})`, "ERROR: eval: 1:1: identifier not found: x"},
+		{`let h = {"n": 1}; [eval("n = 2; n", h), h["n"]]`, "[2, 1]"},
+		{`eval("let = 1")`, "ERROR: eval: 1:5: expected next token to be IDENT, got = instead."},
+		{`eval("1 + true")`, "ERROR: eval: 1:3: type mismatch: INTEGER + BOOLEAN"},
+		{`eval(1)`, "ERROR: argument to `eval` must be STRING, got INTEGER"},
+		{`eval("1", [])`, "ERROR: environment of `eval` must be HASHMAP, got ARRAY"},
+		{`eval("1", {1: 2})`, "ERROR: names in the environment of `eval` must be STRING, got INTEGER"},
+		{`eval()`, "ERROR: wrong number of arguments to eval. got=0, want=1 or 2"},
+		{`let eval = fn(s) { s }; eval("1 + 2")`, "1 + 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil {
+			t.Errorf("%s: no result", tt.input)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestEvalErrorPosition(t *testing.T) {
+	err, ok := testEval("let a = 1;\n  eval(\"a +\")").(*object.Error)
+	if !ok {
+		t.Fatalf("expected an error")
+	}
+	if err.Line != 2 || err.Column != 3 {
+		t.Errorf("error not located at the call. got=%d:%d", err.Line, err.Column)
+	}
+}
@@ -3,6 +3,7 @@ package evaluator
 import (
 	"fmt"
 	"monkey-go/ast"
+	"monkey-go/internal/suggest"
 	"monkey-go/object"
 	"monkey-go/token"
 )
@@ -13,7 +14,17 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
+// Eval evaluates node in env. An error is located at the innermost node it
+// was raised in.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+	if err, ok := result.(*object.Error); ok {
+		locate(err, node)
+	}
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -42,6 +53,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.NullLiteral:
+		return NULL
+
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -55,6 +69,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.ConditionalExpression:
+		return evalConditionalExpression(node, env)
+
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 
@@ -63,26 +80,36 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if err := bindPattern(node.Target(), val, env, node.IsConst()); err != nil {
+			return err
+		}
 		return val
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Env: env, Body: body, Evaluated: inEval}
+
+	case *ast.MacroLiteral:
+		return newError("macro literals may only be bound by a top-level let")
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
+		if isSpecialFormCall(node, "quote") {
+			return quote(node, env)
+		}
+		if isSpecialFormCall(node, "unquote") {
+			return newError("unquote used outside of quote")
+		}
+		if isEnvironmentCall(node, env) {
+			return evalEnvironmentCall(node, env)
 		}
 
-		arg := evalExpressions(node.Arguments, env)
-		if len(arg) == 1 && isError(arg[0]) {
-			return arg[0]
+		function, args, err := evalCallOperands(node, env)
+		if err != nil {
+			return err
 		}
 
-		return applyFunction(function, arg)
+		return applyFunction(node, function, args)
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -104,27 +131,103 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.HashMapLiteral:
 		return evalHashMapLiteral(node, env)
+
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
 	}
 
 	return nil
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	switch fn := fn.(type) {
+// evalCallOperands evaluates the function and the arguments of a call.
+func evalCallOperands(
+	node *ast.CallExpression,
+	env *object.Environment,
+) (object.Object, []object.Object, object.Object) {
+	function := Eval(node.Function, env)
+	if isError(function) {
+		return nil, nil, function
+	}
 
-	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return nil, nil, args[0]
+	}
 
-	case *object.Builtin:
-		return fn.Fn(args...)
+	return function, args, nil
+}
 
-	default:
-		return newError("not a function: %s", fn.Type())
+func applyFunction(call *ast.CallExpression, fn object.Object, args []object.Object) object.Object {
+	defer func(outer bool) { inEval = outer }(inEval)
+
+	// Calls in tail position of a body come back as *tailCall and are run
+	// by the next iteration instead of a nested applyFunction. entered is
+	// the call the hook was told about last; a tail call replacing it is
+	// only reported once it is known to be a call of a reported function.
+	var entered *object.Function
+	var enteredCall *ast.CallExpression
+	for {
+		switch f := fn.(type) {
+
+		case *object.Function:
+			extendedEnv, err := extendFunctionEnv(f, args)
+			if err != nil {
+				locate(err, call)
+				leave(enteredCall, entered, err)
+				return err
+			}
+			inEval = f.Evaluated
+			if hook != nil && !inEval {
+				if entered != nil {
+					hook.Leave(enteredCall, entered, nil)
+				}
+				hook.Enter(call, f, extendedEnv)
+				entered, enteredCall = f, call
+			}
+			evaluated := evalTail(f.Body, extendedEnv, true)
+			if tc, ok := evaluated.(*tailCall); ok {
+				call, fn, args = tc.call, tc.fn, tc.args
+				continue
+			}
+			result := unwrapReturnValue(evaluated)
+			leave(enteredCall, entered, result)
+			return result
+
+		case *object.Builtin:
+			result := f.Fn(args...)
+			if err, ok := result.(*object.Error); ok {
+				locate(err, call)
+			}
+			leave(enteredCall, entered, result)
+			return result
+
+		default:
+			err := newError("not a function: %s", fn.Type())
+			locate(err, call)
+			leave(enteredCall, entered, err)
+			return err
+		}
 	}
 }
 
+// leave notifies the hook that the call of fn ends with result. fn is nil
+// if the hook was not told about a call.
+func leave(call *ast.CallExpression, fn *object.Function, result object.Object) {
+	if hook != nil && fn != nil {
+		hook.Leave(call, fn, result)
+	}
+}
+
+// callExpression stands for the call of a function made by Call rather
+// than by the program, for hooks.
+var callExpression = &ast.CallExpression{Function: &ast.Identifier{Value: "<call>"}}
+
+// Call calls fn, a function or a builtin function, with args and returns
+// the result, as a call expression of the program would.
+func Call(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(callExpression, fn, args)
+}
+
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
@@ -136,14 +239,21 @@ func unwrapReturnValue(obj object.Object) object.Object {
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+) (*object.Environment, *object.Error) {
+	if len(args) != len(fn.Parameters) {
+		return nil, newError("wrong number of arguments. got=%d, want=%d",
+			len(args), len(fn.Parameters))
+	}
+
+	env := object.NewFunctionEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if err := bindPattern(param, args[paramIdx], env, false); err != nil {
+			return nil, err
+		}
 	}
 
-	return env
+	return env, nil
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
@@ -170,6 +280,9 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, stmt := range program.Statements {
+		if err := beforeStatement(stmt, env); err != nil {
+			return err
+		}
 		result = Eval(stmt, env)
 
 		switch rt := result.(type) {
@@ -187,6 +300,9 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 
 	for _, stmt := range block.Statements {
+		if err := beforeStatement(stmt, env); err != nil {
+			return err
+		}
 		result = Eval(stmt, env)
 
 		if result != nil {
@@ -206,6 +322,8 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalTildePrefixOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -220,6 +338,15 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	return &object.Integer{Value: -value}
 }
 
+func evalTildePrefixOperatorExpression(right object.Object) object.Object {
+	if right.Type() != object.INTEGER_OBJ {
+		return newError("unknown operator: ~%s", right.Type())
+	}
+
+	value := right.(*object.Integer).Value
+	return &object.Integer{Value: ^value}
+}
+
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
@@ -227,9 +354,9 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -250,6 +377,28 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
 		return &object.Integer{Value: leftVal / rightVal}
+	case "**":
+		if rightVal < 0 {
+			return newError("negative exponent: %d ** %d", leftVal, rightVal)
+		}
+		return &object.Integer{Value: intPow(leftVal, rightVal)}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d %s %d", leftVal, operator, rightVal)
+		}
+		if rightVal >= 64 {
+			return newError("shift count too large: %d %s %d", leftVal, operator, rightVal)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftVal << rightVal}
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -263,6 +412,20 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+// intPow computes base ** exp for exp >= 0 by repeated squaring.
+// Like the other integer operators it wraps around on overflow.
+func intPow(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
 	if isError(condition) {
@@ -270,14 +433,39 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		takeBranch(ie, 0)
+		return evalScopedBlock(ie.Consequence, env)
+	}
+	takeBranch(ie, 1)
+	if ie.ElseIf != nil {
+		return Eval(ie.ElseIf, env)
 	}
 	if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return evalScopedBlock(ie.Alternative, env)
 	}
 	return NULL
 }
 
+func evalConditionalExpression(ce *ast.ConditionalExpression, env *object.Environment) object.Object {
+	condition := Eval(ce.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		takeBranch(ce, 0)
+		return Eval(ce.Consequence, env)
+	}
+	takeBranch(ce, 1)
+	return Eval(ce.Alternative, env)
+}
+
+// evalScopedBlock evaluates a block in its own scope so that bindings
+// declared inside it do not leak into the enclosing environment.
+func evalScopedBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	return evalBlockStatement(block, object.NewEnclosedEnvironment(env))
+}
+
 func evalInfixExpressionNode(node *ast.InfixExpression, env *object.Environment) object.Object {
 	// 代入式を個別に処理
 	if node.Operator == token.ASSIGN {
@@ -308,8 +496,8 @@ func evalAssignmentExpression(node *ast.InfixExpression, env *object.Environment
 		return val
 	}
 
-	if _, ok := env.Reassign(ident.Value, val); !ok {
-		return newError("identifier not found: " + ident.Value)
+	if _, err := env.Reassign(ident.Value, val); err != nil {
+		return newError("%s: %s", err, ident.Value)
 	}
 	return val
 }
@@ -331,6 +519,24 @@ func newError(format string, a ...any) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// locate sets the position of err to that of node unless it has one. An
+// infix or index expression is located at its operator.
+func locate(err *object.Error, node ast.Node) {
+	if err.Line != 0 || node == nil {
+		return
+	}
+	var tok token.Token
+	switch node := node.(type) {
+	case *ast.InfixExpression:
+		tok = node.Token
+	case *ast.IndexExpression:
+		tok = node.Token
+	default:
+		tok = ast.Start(node)
+	}
+	err.Line, err.Column = tok.Line, tok.Column
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -350,7 +556,21 @@ func evalIdentifier(
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	err := newError("identifier not found: " + node.Value)
+	if name := suggest.Closest(node.Value, namesInScope(env)); name != "" {
+		err.Hint = fmt.Sprintf("did you mean `%s`?", name)
+	}
+	return err
+}
+
+// namesInScope returns the names bound in env and its outer environments
+// and the names of the builtin functions.
+func namesInScope(env *object.Environment) []string {
+	names := BuiltinNames()
+	for ; env != nil; env = env.Outer() {
+		names = append(names, env.Names()...)
+	}
+	return names
 }
 
 func evalExpressions(
@@ -373,14 +593,20 @@ func evalStringInfixExpression(
 	operator string,
 	left, right object.Object,
 ) object.Object {
-	if operator != "+" {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
-
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
 }
 
 func evalIndexExpression(left, index object.Object) object.Object {
@@ -418,8 +644,7 @@ func evalHashMapLiteral(
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
+		if !object.IsHashable(key) {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
@@ -428,7 +653,7 @@ func evalHashMapLiteral(
 			return value
 		}
 
-		hashed := hashKey.HashKey()
+		hashed := key.(object.Hashable).HashKey()
 		pairs[hashed] = object.HashPair{Key: key, Value: value}
 	}
 
@@ -438,12 +663,11 @@ func evalHashMapLiteral(
 func evalHashMapIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.HashMap)
 
-	key, ok := index.(object.Hashable)
-	if !ok {
+	if !object.IsHashable(index) {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Pairs[index.(object.Hashable).HashKey()]
 	if !ok {
 		return NULL
 	}
@@ -1,9 +1,11 @@
 package evaluator
 
 import (
+	"fmt"
 	"monkey-go/lexer"
 	"monkey-go/object"
 	"monkey-go/parser"
+	"runtime/debug"
 	"testing"
 )
 
@@ -27,6 +29,25 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"0xFF + 0o10 + 0b11", 266},
+		{"1_000 * 1_000", 1000000},
+		{"0b1100 & 0b1010", 8},
+		{"0b1100 | 0b1010", 14},
+		{"0b1100 ^ 0b1010", 6},
+		{"~0", -1},
+		{"~5 + 1", -5},
+		{"1 << 10", 1024},
+		{"1024 >> 3", 128},
+		{"-16 >> 2", -4},
+		{"1 << 63 >> 63", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"7 ** 0", 1},
+		{"0 ** 0", 1},
+		{"3 * 2 ** 2", 12},
+		{"0xF0 >> 4 & 0x3", 3},
 	}
 
 	for _, tt := range tests {
@@ -141,6 +162,46 @@ if (10 > 1) {
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			`{[1, {}]: "Monkey"}`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			`{"name": "Monkey"}[[fn(x) { x }]];`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			`"a" < "b"`,
+			"unknown operator: STRING < STRING",
+		},
+		{
+			"1 << -1",
+			"negative shift count: 1 << -1",
+		},
+		{
+			"8 >> -2",
+			"negative shift count: 8 >> -2",
+		},
+		{
+			"1 << 64",
+			"shift count too large: 1 << 64",
+		},
+		{
+			"2 ** -1",
+			"negative exponent: 2 ** -1",
+		},
+		{
+			"~true",
+			"unknown operator: ~BOOLEAN",
+		},
+		{
+			"true & false",
+			"unknown operator: BOOLEAN & BOOLEAN",
+		},
+		{
+			`"a" ** 2`,
+			"type mismatch: STRING ** INTEGER",
+		},
 	}
 
 	for _, tt := range tests {
@@ -207,6 +268,38 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 | 2 == 3", true},
+		{"6 & 3 != 2", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"monkey" == "monkey"`, true},
+		{`"monkey" == "Monkey"`, false},
+		{`"monkey" != "Monkey"`, true},
+		{`[1, 2] == [1, 2]`, true},
+		{`[1, 2] == [2, 1]`, false},
+		{`[1, 2] != [1, 2, 3]`, true},
+		{`[[1, "a"], true] == [[1, "a"], true]`, true},
+		{`[1] == ["1"]`, false},
+		{`let a = [1]; a == a`, true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{} == {}`, true},
+		{`{"a": 1} != {"a": 1}`, false},
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
+		{`[1] == 1`, false},
 	}
 
 	for _, tt := range tests {
@@ -246,6 +339,16 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"if (false) { 1 } else if (false) { 2 } else if (true) { 3 } else { 4 }", 3},
+		{"1 < 2 ? 10 : 20", 10},
+		{"1 > 2 ? 10 : 20", 20},
+		{"false ? 1 : false ? 2 : 3", 3},
+		{"true ? 1 : undefinedName", 1},
+		{"let x = 5; x > 3 ? x * 2 : x", 10},
+		{"let sign = fn(n) { n > 0 ? 1 : n < 0 ? -1 : 0 }; sign(-5)", -1},
 	}
 
 	for _, tt := range tests {
@@ -290,6 +393,8 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 5 * 5; a;", 25},
 		{"let a = 5; let b = a; b;", 5},
 		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let x1 = 2; let x2 = 3; x1 * x2;", 6},
+		{"let 変数 = 7; let 値2 = 変数 + 1; 値2;", 8},
 	}
 
 	for _, tt := range tests {
@@ -297,6 +402,49 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const a = 5; a;", 5},
+		{"const a = 5; let b = a * 2; b;", 10},
+		{"const a = 5; a = 10;", "cannot assign to constant: a"},
+		{"const a = 5; if (true) { a = 10; }", "cannot assign to constant: a"},
+		{"const a = 5; let f = fn() { a = 1 }; f();", "cannot assign to constant: a"},
+		{"const a = 5; if (true) { let a = 10; a }", 10},
+		{"const a = 5; const a = 6;", "identifier already declared: a"},
+		{"let a = 5; const a = 6;", "identifier already declared: a"},
+		{"let a = 5; let a = 6;", "identifier already declared: a"},
+		{"let f = fn(x) { let x = 1; x }; f(2);", "identifier already declared: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		checkInfixTestResult(t, evaluated, tt.expected)
+	}
+}
+
+func TestBlockScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { let y = 1; } y;", "identifier not found: y"},
+		{"if (false) { 1 } else { let y = 2; } y;", "identifier not found: y"},
+		{"let x = 1; if (true) { let x = 2; } x;", 1},
+		{"let x = 1; if (true) { let x = 2; x } ", 2},
+		{"let x = 1; if (true) { x = 2; } x;", 2},
+		{"let x = 1; if (true) { if (true) { x = x + 1; } } x;", 2},
+		{"if (true) { let y = 1; } if (true) { let y = 2; y }", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		checkInfixTestResult(t, evaluated, tt.expected)
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -340,6 +488,239 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a + b;", 3},
+		{"let [a, ...rest] = [1, 2, 3]; len(rest);", 2},
+		{"let [a, ...rest] = [1, 2, 3]; rest[1];", 3},
+		{"let [a, ...rest] = [1]; len(rest);", 0},
+		{"let [a, [b, c]] = [1, [2, 3]]; a + b + c;", 6},
+		{`let {name, age} = {"name": "Monkey", "age": 3}; name;`, "Monkey"},
+		{`let {name, age} = {"name": "Monkey", "age": 3}; age;`, 3},
+		{`let {"age": years} = {"age": 3}; years;`, 3},
+		{`let {1: one, true: yes} = {1: 10, true: 20}; one + yes;`, 30},
+		{`let {pos: [x, y]} = {"pos": [4, 5]}; x * y;`, 20},
+		{`let [{x}, {x: y}] = [{"x": 1}, {"x": 2}]; x + y;`, 3},
+		{"let f = fn([a, b]) { a * b }; f([3, 4]);", 12},
+		{`let greet = fn({name}) { "Hi " + name }; greet({"name": "Monkey"});`, "Hi Monkey"},
+		{"let sum = fn([x, ...xs]) { if (len(xs) == 0) { x } else { x + sum(xs) } }; sum([1, 2, 3, 4]);", 10},
+		{"const [a, b] = [1, 2]; a = 5;", "cannot assign to constant: a"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		checkInfixTestResult(t, evaluated, tt.expected)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let [a, b] = 1;", "cannot destructure INTEGER as ARRAY"},
+		{"let [a, b] = [1];", "array has 1 elements, pattern [a, b] expects 2"},
+		{"let [a, b] = [1, 2, 3];", "array has 3 elements, pattern [a, b] expects 2"},
+		{"let [a, b, ...c] = [1];", "array has 1 elements, pattern [a, b, ...c] expects at least 2"},
+		{`let {name} = [1];`, "cannot destructure ARRAY as HASHMAP"},
+		{`let {name, age} = {"name": "Monkey"};`, `hashmap has no key "age"`},
+		{`let {1: one} = {"1": 1};`, "hashmap has no key 1"},
+		{"let [a, a] = [1, 2];", "identifier already declared: a"},
+		{"let f = fn([a, b]) { a }; f([1]);", "array has 1 elements, pattern [a, b] expects 2"},
+		{"let f = fn(a, b) { a }; f(1);", "wrong number of arguments. got=1, want=2"},
+		{"let f = fn(a) { a }; f(1, 2);", "wrong number of arguments. got=2, want=1"},
+		{"let f = fn(a, a) { a }; f(1, 2);", "identifier already declared: a"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	describe := `
+let describe = fn(x) {
+	match (x) {
+		0 => "zero",
+		-1 => "minus one",
+		"hi" => "greeting",
+		true => "yes",
+		[] => "empty",
+		[a] => "one element",
+		[a, b] if a == b => "pair of equals",
+		[a, b] => "pair",
+		[a, ...rest] => "list starting with " + a,
+		{"type": "circle", r} => "circle",
+		{"type": t} => "shape " + t,
+		n if n == 1000 => "thousand",
+		_ => "other",
+	}
+};
+`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{describe + "describe(0)", "zero"},
+		{describe + "describe(-1)", "minus one"},
+		{describe + `describe("hi")`, "greeting"},
+		{describe + "describe(true)", "yes"},
+		{describe + "describe(false)", "other"},
+		{describe + "describe([])", "empty"},
+		{describe + "describe([1])", "one element"},
+		{describe + "describe([2, 2])", "pair of equals"},
+		{describe + "describe([1, 2])", "pair"},
+		{describe + `describe(["x", 2, 3])`, "list starting with x"},
+		{describe + `describe({"type": "circle", "r": 1})`, "circle"},
+		{describe + `describe({"type": "square"})`, "shape square"},
+		{describe + "describe(1000)", "thousand"},
+		{describe + "describe(7)", "other"},
+		{"match ([1, 2]) { [a, b] => a + b }", 3},
+		{"let x = 1; match (5) { x => x }", 5},
+		{"let x = 1; match (5) { x => x }; x", 1},
+		{"match ({\"p\": [1, 2]}) { {p: [x, y]} => x * 10 + y }", 12},
+		{"let f = fn(n) { match (n) { 0 => 1, n => n * f(n - 1) } }; f(5)", 120},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		checkInfixTestResult(t, evaluated, tt.expected)
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"match (3) { 1 => 1, 2 => 2 }", "no match arm for value 3"},
+		{`match ("3") { 3 => 1 }`, `no match arm for value "3"`},
+		{"match ([1, 2]) { [a, a] => a }", "identifier already declared: a"},
+		{"match (1) { n if n + true => n }", "type mismatch: INTEGER + BOOLEAN"},
+		{"match (foo) { _ => 1 }", "identifier not found: foo"},
+		{"let [1, x] = [2, 3];", "value 2 does not match pattern 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	// Without tail calls every iteration below nests several Go frames, so
+	// even the shorter loops would need far more stack than this.
+	defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			`let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } };
+			count(1000000, 0);`,
+			1000000,
+		},
+		{
+			`let count = fn(n, acc) { if (n == 0) { return acc; } return count(n - 1, acc + 1); };
+			count(100000, 0);`,
+			100000,
+		},
+		{
+			`let count = fn(n, acc) { if (n > 0) { return count(n - 1, acc + 1); } acc };
+			count(100000, 0);`,
+			100000,
+		},
+		{
+			`let count = fn(n, acc) { n == 0 ? acc : count(n - 1, acc + 1) };
+			count(100000, 0);`,
+			100000,
+		},
+		{
+			`let count = fn(n, acc) { match (n) { 0 => acc, _ => count(n - 1, acc + 1) } };
+			count(100000, 0);`,
+			100000,
+		},
+		{
+			`let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+			let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+			isEven(100001) ? "even" : "odd";`,
+			"odd",
+		},
+		{
+			`let sum = fn(arr, i, acc) {
+				if (i == len(arr)) { return acc; }
+				sum(arr, i + 1, acc + arr[i])
+			};
+			sum([1, 2, 3, 4, 5], 0, 0);`,
+			15,
+		},
+		{
+			// closures keep the environment they were created in
+			`let makeCounter = fn(step) {
+				let loop = fn(n, acc) { if (n == 0) { acc } else { loop(n - 1, acc + step) } };
+				loop
+			};
+			let byThree = makeCounter(3);
+			byThree(100000, 0);`,
+			300000,
+		},
+		{
+			// a call that is not the last statement still runs for its effect
+			`let total = 0;
+			let add = fn(n) { total = total + n; };
+			let f = fn() { add(1); if (true) { add(2); } add(3); total };
+			f();`,
+			6,
+		},
+		{
+			`let inc = fn(x) { x + 1 }; let f = fn(x) { inc(x) * 2 }; f(1);`,
+			4,
+		},
+		{
+			`let f = fn(x) { len(x) }; f("four");`,
+			4,
+		},
+		{
+			`let f = fn() { 5() }; f();`,
+			"not a function: INTEGER",
+		},
+		{
+			`let g = fn(a, b) { a }; let f = fn() { g(1) }; f();`,
+			"wrong number of arguments. got=1, want=2",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		checkInfixTestResult(t, evaluated, tt.expected)
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 let newAdder = fn(x){
@@ -615,6 +996,18 @@ func TestHashMapIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{[1, "a"]: 5}[[1, "a"]]`,
+			5,
+		},
+		{
+			`let point = [1, 2]; {point: 5}[[1, 1 + 1]]`,
+			5,
+		},
+		{
+			`{[1, 2]: 5}[[2, 1]]`,
+			nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -829,3 +1222,31 @@ func checkStringOrError(t *testing.T, evaluated object.Object, expected string)
 		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // line:column: message (hint)
+	}{
+		{"let x = 1;\nx + true", "2:3: type mismatch: INTEGER + BOOLEAN ()"},
+		{"let f = fn() {\n  -true\n};\nf()", "2:3: unknown operator: -BOOLEAN ()"},
+		{"[1][true]", "1:4: index operator not supported: ARRAY ()"},
+		{"len(1, 2)", "1:1: wrong number of arguments. got=2, want=1 ()"},
+		{"let f = fn(a) { a };\nlet g = fn() { f() };\ng()", "2:16: wrong number of arguments. got=0, want=1 ()"},
+		{"let length = 1;\nfn() { lenght }()", "2:8: identifier not found: lenght (did you mean `length`?)"},
+		{"pirnt(1)", "1:1: identifier not found: pirnt (did you mean `print`?)"},
+		{"foobar", "1:1: identifier not found: foobar ()"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		got := fmt.Sprintf("%d:%d: %s (%s)", errObj.Line, errObj.Column, errObj.Message, errObj.Hint)
+		if got != tt.expected {
+			t.Errorf("%q: wrong error.\nwant=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
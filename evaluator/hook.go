@@ -0,0 +1,103 @@
+package evaluator
+
+import (
+	"monkey-go/ast"
+	"monkey-go/object"
+)
+
+// Hook is notified by Eval while a program runs. Tools such as the debugger
+// use it to follow the execution. Code run by eval, and the functions it
+// defines, are not reported.
+type Hook interface {
+	// Statement is called before stmt is evaluated in env. If it returns
+	// an error, stmt is not evaluated and the error becomes the result of
+	// the program.
+	Statement(stmt ast.Statement, env *object.Environment) *object.Error
+
+	// Enter is called when a call of fn starts, after its parameters have
+	// been bound in env.
+	Enter(call *ast.CallExpression, fn *object.Function, env *object.Environment)
+
+	// Leave is called when the call started by the matching Enter ends. A
+	// call of a function in tail position replaces the current call: Leave
+	// is called with a nil result and always followed by Enter for the new
+	// call. A builtin called in tail position ends the current call with
+	// its result.
+	Leave(call *ast.CallExpression, fn *object.Function, result object.Object)
+}
+
+// BranchHook is a Hook that is also told which way the program branches,
+// e.g. to measure branch coverage.
+type BranchHook interface {
+	Hook
+
+	// Branch is called when node, an *ast.IfExpression,
+	// *ast.ConditionalExpression or *ast.MatchExpression, takes branch i:
+	// 0 for the consequence and 1 for the alternative of a condition, also
+	// for an if without else, or the index of the selected match arm.
+	Branch(node ast.Expression, i int)
+}
+
+// Interrupter is a Hook that can also stop the code it is not told about,
+// the code run by eval and the functions it defines, e.g. to stop a
+// program spinning inside eval.
+type Interrupter interface {
+	Hook
+
+	// Interrupt is called before every statement of that code. If it
+	// returns an error, the statement is not evaluated and the error
+	// becomes the result of the program.
+	Interrupt() *object.Error
+}
+
+// hook is the installed Hook, if any. Eval is not safe for concurrent use
+// while a hook is installed.
+var hook Hook
+
+// inEval reports whether the code being run was given to eval, or defined
+// by such code. Its positions are those of a string rather than of the
+// program, so the hook is not told about it.
+var inEval bool
+
+// SetHook installs h and returns the hook it replaces. A nil h removes the
+// hook.
+func SetHook(h Hook) Hook {
+	old := hook
+	hook = h
+	return old
+}
+
+// stopped is the last error the hook stopped the program with. eval
+// passes it on as it is rather than as an error of the code it runs.
+var stopped *object.Error
+
+// beforeStatement notifies the hook of stmt and returns the error that
+// stops the program, if any.
+func beforeStatement(stmt ast.Statement, env *object.Environment) *object.Error {
+	var err *object.Error
+	switch h := hook.(type) {
+	case nil:
+		return nil
+	case Interrupter:
+		if inEval {
+			err = h.Interrupt()
+		} else {
+			err = h.Statement(stmt, env)
+		}
+	default:
+		if !inEval {
+			err = h.Statement(stmt, env)
+		}
+	}
+	if err != nil {
+		stopped = err
+	}
+	return err
+}
+
+// takeBranch notifies a BranchHook that node takes branch i.
+func takeBranch(node ast.Expression, i int) {
+	if h, ok := hook.(BranchHook); ok && !inEval {
+		h.Branch(node, i)
+	}
+}
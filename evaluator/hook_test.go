@@ -0,0 +1,144 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/object"
+	"strings"
+	"testing"
+)
+
+// recorder is a Hook that records what it is told.
+type recorder struct {
+	events []string
+	stopAt int // line at which Statement stops the program, 0 for none
+}
+
+func (r *recorder) Statement(stmt ast.Statement, env *object.Environment) *object.Error {
+	line := ast.Start(stmt).Line
+	r.events = append(r.events, fmt.Sprintf("stmt %d", line))
+	if line == r.stopAt {
+		return newError("stopped at line %d", line)
+	}
+	return nil
+}
+
+func (r *recorder) Enter(call *ast.CallExpression, fn *object.Function, env *object.Environment) {
+	r.events = append(r.events, fmt.Sprintf("enter %s %s", call.Function, strings.Join(env.Names(), ",")))
+}
+
+func (r *recorder) Leave(call *ast.CallExpression, fn *object.Function, result object.Object) {
+	value := "<tail call>"
+	if result != nil {
+		value = result.Inspect()
+	}
+	r.events = append(r.events, fmt.Sprintf("leave %s %s", call.Function, value))
+}
+
+func TestHook(t *testing.T) {
+	tests := []struct {
+		input    string
+		stopAt   int
+		expected []string
+	}{
+		{
+			"let add = fn(a, b) {\n a + b\n};\nadd(1, add(2, 3))",
+			0,
+			[]string{
+				"stmt 1", "stmt 4",
+				"enter add a,b", "stmt 2", "leave add 5",
+				"enter add a,b", "stmt 2", "leave add 6",
+			},
+		},
+		{
+			// a tail call replaces the current call
+			"let f = fn(n) {\n if (n == 0) {\n 0\n } else {\n f(n - 1)\n }\n};\nf(1)",
+			0,
+			[]string{
+				"stmt 1", "stmt 8",
+				"enter f n", "stmt 2", "stmt 5", "leave f <tail call>",
+				"enter f n", "stmt 2", "stmt 3", "leave f 0",
+			},
+		},
+		{
+			// a builtin called in tail position ends the call
+			"let f = fn(a) {\n len(a)\n};\nf(\"ab\")",
+			0,
+			[]string{"stmt 1", "stmt 4", "enter f a", "stmt 2", "leave f 2"},
+		},
+		{
+			// the code run by eval and the functions it defines are not
+			// reported, the functions of the program it calls are
+			"let f = fn(a) {\n a\n};\nlet g = eval(\"\n\nf(1); fn(n) { n }\");\nlet h = fn(n) {\n g(n)\n};\nh(2)",
+			0,
+			[]string{
+				"stmt 1", "stmt 4", "enter f a", "stmt 2", "leave f 1",
+				"stmt 7", "stmt 10", "enter h n", "stmt 8", "leave h 2",
+			},
+		},
+		{
+			"let x = 1;\nlet f = fn() {\n let y = 2;\n y\n};\nf();\nx",
+			3,
+			[]string{"stmt 1", "stmt 2", "stmt 6", "enter f ", "stmt 3", "leave f ERROR: stopped at line 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		r := &recorder{stopAt: tt.stopAt}
+		old := SetHook(r)
+		evaluated := testEval(tt.input)
+		SetHook(old)
+
+		if strings.Join(r.events, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong events for %q.\nwant=%q\ngot=%q", tt.input, tt.expected, r.events)
+		}
+		if tt.stopAt == 0 {
+			continue
+		}
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || errObj.Message != fmt.Sprintf("stopped at line %d", tt.stopAt) {
+			t.Errorf("program not stopped at line %d. got=%T (%+v)", tt.stopAt, evaluated, evaluated)
+		}
+	}
+}
+
+// branchRecorder is a recorder that also records branches.
+type branchRecorder struct {
+	recorder
+}
+
+func (r *branchRecorder) Branch(node ast.Expression, i int) {
+	r.events = append(r.events, fmt.Sprintf("branch %d:%d %d", ast.Start(node).Line, ast.Start(node).Column, i))
+}
+
+func TestBranchHook(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"if (true) { 1 }", []string{"stmt 1", "branch 1:1 0", "stmt 1"}},
+		{"if (false) { 1 }", []string{"stmt 1", "branch 1:1 1"}},
+		{"if (1 > 2) { 1 } else { 2 }", []string{"stmt 1", "branch 1:1 1", "stmt 1"}},
+		{"false ? 1 : 2", []string{"stmt 1", "branch 1:1 1"}},
+		{"match (2) { 1 => 1, n if n > 1 => n, _ => 0 }", []string{"stmt 1", "branch 1:1 1"}},
+		{
+			// branches in tail position
+			"let f = fn(n) {\n if (n > 0) { n > 1 ? 1 : 2 } else { match (n) { _ => 0 } }\n};\nf(1); f(0)",
+			[]string{
+				"stmt 1", "stmt 4", "enter f n", "stmt 2", "branch 2:2 0", "stmt 2", "branch 2:15 1", "leave f 2",
+				"stmt 4", "enter f n", "stmt 2", "branch 2:2 1", "stmt 2", "branch 2:38 0", "leave f 0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		r := &branchRecorder{}
+		old := SetHook(r)
+		testEval(tt.input)
+		SetHook(old)
+
+		if strings.Join(r.events, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong events for %q.\nwant=%q\ngot=%q", tt.input, tt.expected, r.events)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"monkey-go/ast"
+	"monkey-go/object"
+)
+
+// typePredicates are the is_ builtins with the types of the values they
+// accept and a description of them.
+var typePredicates = map[string]struct {
+	types       []object.ObjectType
+	description string
+}{
+	"is_int":    {[]object.ObjectType{object.INTEGER_OBJ}, "an integer"},
+	"is_bool":   {[]object.ObjectType{object.BOOLEAN_OBJ}, "a boolean"},
+	"is_string": {[]object.ObjectType{object.STRING_OBJ}, "a string"},
+	"is_null":   {[]object.ObjectType{object.NULL_OBJ}, "null"},
+	"is_array":  {[]object.ObjectType{object.ARRAY_OBJ}, "an array"},
+	"is_hash":   {[]object.ObjectType{object.HASHMAP_OBJ}, "a hash"},
+	"is_fn":     {[]object.ObjectType{object.FUNCTION_OBJ, object.BUILTIN_OBJ}, "a function or a builtin function"},
+}
+
+func init() {
+	register("type", &object.Builtin{
+		Doc: "type(x) returns the type of x as a string, such as \"INTEGER\" or \"FUNCTION\".",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+			return &object.String{Value: string(args[0].Type())}
+		},
+	})
+
+	for name, predicate := range typePredicates {
+		types := predicate.types
+		register(name, &object.Builtin{
+			Doc: name + "(x) reports whether x is " + predicate.description + ".",
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1",
+						len(args))
+				}
+				for _, t := range types {
+					if args[0].Type() == t {
+						return TRUE
+					}
+				}
+				return FALSE
+			},
+		})
+	}
+
+	register("arity", &object.Builtin{
+		Doc: "arity(fn) returns the number of parameters of the function fn.",
+		Fn: func(args ...object.Object) object.Object {
+			fn, err := functionArgument("arity", args)
+			if err != nil {
+				return err
+			}
+			return &object.Integer{Value: int64(len(fn.Parameters))}
+		},
+	})
+
+	register("params", &object.Builtin{
+		Doc: "params(fn) returns the parameters of the function fn as an array of strings, such as [\"a\", \"[x, y]\"].",
+		Fn: func(args ...object.Object) object.Object {
+			fn, err := functionArgument("params", args)
+			if err != nil {
+				return err
+			}
+			params := make([]object.Object, len(fn.Parameters))
+			for i, p := range fn.Parameters {
+				params[i] = &object.String{Value: p.String()}
+			}
+			return &object.Array{Elements: params}
+		},
+	})
+}
+
+// functionArgument returns the only argument of the builtin name, which
+// must be a function.
+func functionArgument(name string, args []object.Object) (*object.Function, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	fn, ok := args[0].(*object.Function)
+	if !ok {
+		return nil, newError("argument to `%s` must be FUNCTION, got %s", name, args[0].Type())
+	}
+	return fn, nil
+}
+
+// isEnvironmentCall reports whether call is a call of one of the special
+// forms that need the environment of the caller, globals, locals and eval:
+// the name is not bound in env.
+func isEnvironmentCall(call *ast.CallExpression, env *object.Environment) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || ident.Value != "globals" && ident.Value != "locals" && ident.Value != "eval" {
+		return false
+	}
+	_, bound := env.Get(ident.Value)
+	return !bound
+}
+
+// evalEnvironmentCall evaluates a call of globals, locals or eval. globals
+// and locals return the bindings they read as a hash: globals those of the
+// outermost scope, locals those of the scopes from the current block up to
+// the scope of the function call, or of the program, an inner binding
+// shadowing an outer one.
+func evalEnvironmentCall(call *ast.CallExpression, env *object.Environment) object.Object {
+	name := call.Function.(*ast.Identifier).Value
+	if name == "eval" {
+		return evalEvalCall(call, env)
+	}
+	if len(call.Arguments) != 0 {
+		return newError("wrong number of arguments to %s. got=%d, want=0", name, len(call.Arguments))
+	}
+	if name == "globals" {
+		for env.Outer() != nil {
+			env = env.Outer()
+		}
+	}
+
+	hash := &object.HashMap{Pairs: map[object.HashKey]object.HashPair{}}
+	for scope := env; scope != nil; scope = scope.Outer() {
+		for _, n := range scope.Names() {
+			key := &object.String{Value: n}
+			if _, shadowed := hash.Pairs[key.HashKey()]; shadowed {
+				continue
+			}
+			value, _ := scope.Get(n)
+			hash.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		if scope.IsFunction() {
+			break
+		}
+	}
+	return hash
+}
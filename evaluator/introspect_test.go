@@ -0,0 +1,56 @@
+package evaluator
+
+import (
+	"testing"
+)
+
+func TestIntrospection(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the inspected result
+	}{
+		{`null`, "null"},
+		{`let x = null; x == null`, "true"},
+		{`if (null) { 1 } else { 2 }`, "2"},
+		{`first([]) == null`, "true"},
+		{`match (null) { null => "none", _ => "some" }`, "none"},
+		{`quote(unquote(null))`, "QUOTE(null)"},
+		{`type(1)`, "INTEGER"},
+		{`type("a")`, "STRING"},
+		{`type(null)`, "NULL"},
+		{`type([])`, "ARRAY"},
+		{`type({})`, "HASHMAP"},
+		{`type(fn() {})`, "FUNCTION"},
+		{`type(len)`, "BUILTIN"},
+		{`type(1, 2)`, "ERROR: wrong number of arguments. got=2, want=1"},
+		{`[is_int(1), is_int("1"), is_bool(false), is_string("s"), is_null(null)]`, "[true, false, true, true, true]"},
+		{`[is_array([]), is_hash({}), is_fn(fn() {}), is_fn(len), is_fn(1)]`, "[true, true, true, true, false]"},
+		{`arity(fn(a, [b, c]) { a })`, "2"},
+		{`params(fn(a, [b, c], {d}) { a })`, "[a, [b, c], {d}]"},
+		{`params(fn() { 1 })`, "[]"},
+		{`arity(len)`, "ERROR: argument to `arity` must be FUNCTION, got BUILTIN"},
+		{`len`, "builtin function len"},
+		{`assertEq`, "builtin function assertEq"},
+		{`let a = 1; let f = fn(b) { let c = 2; let l = locals(); [l["a"], l["b"], l["c"]] }; f(3)`, "[null, 3, 2]"},
+		{`let f = fn(b) { locals() }; f(3)`, "{b: 3}"},
+		{`fn(a) { if (true) { locals() } }(1)`, "{a: 1}"},
+		{`fn(a) { match (2) { x => [locals()["a"], locals()["x"]] } }(1)`, "[1, 2]"},
+		{`fn(x) { if (true) { let x = 2; locals() } }(1)`, "{x: 2}"},
+		{`let a = 1; if (true) { let b = 2; let l = locals(); [l["a"], l["b"]] }`, "[1, 2]"},
+		{`let a = 1; let f = fn(b) { globals() }; f(2)["a"]`, "1"},
+		{`let a = 1; locals()["a"]`, "1"},
+		{`let locals = fn() { "mine" }; locals()`, "mine"},
+		{`globals(1)`, "ERROR: wrong number of arguments to globals. got=1, want=0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated == nil {
+			t.Errorf("%s: no result", tt.input)
+			continue
+		}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
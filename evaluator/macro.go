@@ -0,0 +1,225 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/object"
+	"monkey-go/token"
+)
+
+// isSpecialFormCall reports whether call is quote(...) or unquote(...),
+// which are handled by the evaluator instead of being looked up as
+// functions.
+func isSpecialFormCall(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+// quote returns its argument as an unevaluated AST, except for unquote(...)
+// calls inside it, which are evaluated and spliced back in.
+func quote(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 {
+		return newError("wrong number of arguments to quote. got=%d, want=1", len(call.Arguments))
+	}
+
+	var err object.Object
+	node := ast.Modify(call.Arguments[0], func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if err != nil || !ok || !isSpecialFormCall(call, "unquote") {
+			return node
+		}
+		if len(call.Arguments) != 1 {
+			err = newError("wrong number of arguments to unquote. got=%d, want=1", len(call.Arguments))
+			return node
+		}
+
+		unquoted := Eval(call.Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted
+			return node
+		}
+		converted := convertObjectToASTNode(unquoted)
+		if converted == nil {
+			err = newError("cannot unquote %s", unquoted.Type())
+			return node
+		}
+		return converted
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Quote{Node: node}
+}
+
+// convertObjectToASTNode turns a value back into source form so that it can
+// be spliced into a quoted tree. It returns nil for values that have no
+// literal syntax.
+func convertObjectToASTNode(obj object.Object) ast.Node {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		literal := fmt.Sprintf("%d", obj.Value)
+		t := token.Token{Type: token.INT, Literal: literal}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}
+
+	case *object.Boolean:
+		var t token.Token
+		if obj.Value {
+			t = token.Token{Type: token.TRUE, Literal: "true"}
+		} else {
+			t = token.Token{Type: token.FALSE, Literal: "false"}
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}
+
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}
+
+	case *object.Null:
+		return &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "null"}}
+
+	case *object.Quote:
+		return obj.Node
+	}
+
+	return nil
+}
+
+// DefineMacros moves the top-level `let name = macro(...) { ... };`
+// statements of program into env and removes them from the program.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	definitions := []int{}
+
+	for i, statement := range program.Statements {
+		if isMacroDefinition(statement) {
+			addMacro(statement, env)
+			definitions = append(definitions, i)
+		}
+	}
+
+	for i := len(definitions) - 1; i >= 0; i = i - 1 {
+		definitionIndex := definitions[i]
+		program.Statements = append(
+			program.Statements[:definitionIndex],
+			program.Statements[definitionIndex+1:]...,
+		)
+	}
+}
+
+func isMacroDefinition(node ast.Statement) bool {
+	letStatement, ok := node.(*ast.LetStatement)
+	if !ok || letStatement.Name == nil {
+		return false
+	}
+
+	_, ok = letStatement.Value.(*ast.MacroLiteral)
+	return ok
+}
+
+func addMacro(stmt ast.Statement, env *object.Environment) {
+	letStatement, _ := stmt.(*ast.LetStatement)
+	macroLiteral, _ := letStatement.Value.(*ast.MacroLiteral)
+
+	macro := &object.Macro{
+		Parameters: macroLiteral.Parameters,
+		Env:        env,
+		Body:       macroLiteral.Body,
+	}
+
+	env.Set(letStatement.Name.Value, macro)
+}
+
+// ExpandMacros replaces every call of a macro defined in env with the AST
+// the macro returns. The macro receives its arguments quoted, i.e. as
+// unevaluated ASTs, and must return a quote.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if err != nil {
+			return node
+		}
+		callExpression, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+
+		macro, ok := isMacroCall(callExpression, env)
+		if !ok {
+			return node
+		}
+
+		var evalEnv *object.Environment
+		evalEnv, err = extendMacroEnv(macro, quoteArgs(callExpression))
+		if err != nil {
+			return node
+		}
+
+		evaluated := unwrapReturnValue(Eval(macro.Body, evalEnv))
+		if e, ok := evaluated.(*object.Error); ok {
+			err = e
+			return node
+		}
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			err = newError("macro must return a quote, got %s", typeOf(evaluated))
+			return node
+		}
+
+		return quote.Node
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expanded, nil
+}
+
+func isMacroCall(exp *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	identifier, ok := exp.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+	args := []*object.Quote{}
+
+	for _, a := range exp.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
+
+	return args
+}
+
+func extendMacroEnv(macro *object.Macro, args []*object.Quote) (*object.Environment, *object.Error) {
+	if len(args) != len(macro.Parameters) {
+		return nil, newError("wrong number of arguments to macro. got=%d, want=%d",
+			len(args), len(macro.Parameters))
+	}
+
+	extended := object.NewFunctionEnvironment(macro.Env)
+
+	for paramIdx, param := range macro.Parameters {
+		if err := bindPattern(param, args[paramIdx], extended, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return extended, nil
+}
+
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
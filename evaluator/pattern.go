@@ -0,0 +1,210 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/object"
+)
+
+// bindPattern destructures val according to pattern and declares every
+// identifier in the pattern in env. It is used where a pattern must match,
+// so a value of the wrong shape is an error.
+func bindPattern(
+	pattern ast.Expression,
+	val object.Object,
+	env *object.Environment,
+	constant bool,
+) *object.Error {
+	mismatch, err := matchPattern(pattern, val, env, constant)
+	if err != nil {
+		return err
+	}
+	if mismatch != "" {
+		return newError("%s", mismatch)
+	}
+	return nil
+}
+
+// matchPattern checks whether val has the shape described by pattern and
+// declares the pattern's bindings in env while doing so. A value that does
+// not fit is reported through mismatch; err is reserved for failures that
+// are errors regardless of the value, such as a name bound twice.
+//
+// Bindings made before a mismatch is found are left in env, so callers that
+// try several patterns should give each attempt its own environment.
+func matchPattern(
+	pattern ast.Expression,
+	val object.Object,
+	env *object.Environment,
+	constant bool,
+) (mismatch string, err *object.Error) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return "", nil
+		}
+		if _, err := env.Declare(pattern.Value, val, constant); err != nil {
+			return "", newError("%s: %s", err, pattern.Value)
+		}
+		return "", nil
+
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.NullLiteral, *ast.PrefixExpression:
+		return matchLiteralPattern(pattern, val, env)
+
+	case *ast.ArrayPattern:
+		return matchArrayPattern(pattern, val, env, constant)
+
+	case *ast.HashPattern:
+		return matchHashPattern(pattern, val, env, constant)
+
+	default:
+		return "", newError("invalid pattern: %s", pattern.String())
+	}
+}
+
+func matchLiteralPattern(
+	pattern ast.Expression,
+	val object.Object,
+	env *object.Environment,
+) (string, *object.Error) {
+	literal := Eval(pattern, env)
+	if isError(literal) {
+		return "", literal.(*object.Error)
+	}
+
+	if !object.Equal(literal, val) {
+		return fmt.Sprintf("value %s does not match pattern %s",
+			inspectKey(val), inspectKey(literal)), nil
+	}
+	return "", nil
+}
+
+func matchArrayPattern(
+	pattern *ast.ArrayPattern,
+	val object.Object,
+	env *object.Environment,
+	constant bool,
+) (string, *object.Error) {
+	arr, ok := val.(*object.Array)
+	if !ok {
+		return fmt.Sprintf("cannot destructure %s as ARRAY", val.Type()), nil
+	}
+
+	want := len(pattern.Elements)
+	got := len(arr.Elements)
+	if pattern.Rest == nil && got != want {
+		return fmt.Sprintf("array has %d elements, pattern %s expects %d",
+			got, pattern.String(), want), nil
+	}
+	if pattern.Rest != nil && got < want {
+		return fmt.Sprintf("array has %d elements, pattern %s expects at least %d",
+			got, pattern.String(), want), nil
+	}
+
+	for i, element := range pattern.Elements {
+		mismatch, err := matchPattern(element, arr.Elements[i], env, constant)
+		if mismatch != "" || err != nil {
+			return mismatch, err
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := make([]object.Object, got-want)
+		copy(rest, arr.Elements[want:])
+		return matchPattern(pattern.Rest, &object.Array{Elements: rest}, env, constant)
+	}
+
+	return "", nil
+}
+
+func matchHashPattern(
+	pattern *ast.HashPattern,
+	val object.Object,
+	env *object.Environment,
+	constant bool,
+) (string, *object.Error) {
+	hash, ok := val.(*object.HashMap)
+	if !ok {
+		return fmt.Sprintf("cannot destructure %s as HASHMAP", val.Type()), nil
+	}
+
+	for _, pair := range pattern.Pairs {
+		key := Eval(pair.Key, env)
+		if isError(key) {
+			return "", key.(*object.Error)
+		}
+		if !object.IsHashable(key) {
+			return "", newError("unusable as hash key: %s", key.Type())
+		}
+
+		found, ok := hash.Pairs[key.(object.Hashable).HashKey()]
+		if !ok {
+			return fmt.Sprintf("hashmap has no key %s", inspectKey(key)), nil
+		}
+
+		mismatch, err := matchPattern(pair.Value, found.Value, env, constant)
+		if mismatch != "" || err != nil {
+			return mismatch, err
+		}
+	}
+
+	return "", nil
+}
+
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	armEnv, arm, err := selectMatchArm(me, env)
+	if err != nil {
+		return err
+	}
+
+	return Eval(arm.Body, armEnv)
+}
+
+// selectMatchArm evaluates the subject of me and finds the first arm whose
+// pattern and guard accept it. It returns the arm together with the
+// environment holding the arm's bindings.
+func selectMatchArm(
+	me *ast.MatchExpression,
+	env *object.Environment,
+) (*object.Environment, *ast.MatchArm, object.Object) {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return nil, nil, subject
+	}
+
+	for i, arm := range me.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+
+		mismatch, err := matchPattern(arm.Pattern, subject, armEnv, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if mismatch != "" {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return nil, nil, guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		takeBranch(me, i)
+		return armEnv, arm, nil
+	}
+
+	return nil, nil, newError("no match arm for value %s", inspectKey(subject))
+}
+
+// inspectKey renders a value for error messages, quoting strings so that
+// "1" and 1 can be told apart.
+func inspectKey(key object.Object) string {
+	if str, ok := key.(*object.String); ok {
+		return `"` + str.Value + `"`
+	}
+	return key.Inspect()
+}
@@ -0,0 +1,135 @@
+package evaluator
+
+import (
+	"monkey-go/ast"
+	"monkey-go/object"
+)
+
+// tailCall is a call found in tail position of a function body. Instead of
+// performing it, evalTail hands it back to applyFunction, which runs it in
+// place of the current call so that tail recursion does not grow the Go
+// stack. A tailCall never escapes applyFunction.
+type tailCall struct {
+	call *ast.CallExpression
+	fn   object.Object
+	args []object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call" }
+
+// evalTail evaluates a node of a function body. If result is true the value
+// of node becomes the result of the function, so a call there can be
+// returned as a *tailCall. Otherwise only calls under `return` are in tail
+// position. Everything else is left to Eval.
+func evalTail(node ast.Node, env *object.Environment, result bool) object.Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		return evalTailBlockStatement(node, env, result)
+
+	case *ast.ExpressionStatement:
+		return evalTail(node.Expression, env, result)
+
+	case *ast.ReturnStatement:
+		val := evalTail(node.ReturnValue, env, true)
+		if isError(val) || isTailCall(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+
+	case *ast.IfExpression:
+		return evalTailIfExpression(node, env, result)
+
+	case *ast.CallExpression:
+		if !result || isSpecialFormCall(node, "quote") || isSpecialFormCall(node, "unquote") ||
+			isEnvironmentCall(node, env) {
+			break
+		}
+		function, args, err := evalCallOperands(node, env)
+		if err != nil {
+			return err
+		}
+		return &tailCall{call: node, fn: function, args: args}
+
+	case *ast.ConditionalExpression:
+		if !result {
+			break
+		}
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			takeBranch(node, 0)
+			return evalTail(node.Consequence, env, true)
+		}
+		takeBranch(node, 1)
+		return evalTail(node.Alternative, env, true)
+
+	case *ast.MatchExpression:
+		if !result {
+			break
+		}
+		armEnv, arm, err := selectMatchArm(node, env)
+		if err != nil {
+			return err
+		}
+		return evalTail(arm.Body, armEnv, true)
+	}
+
+	return Eval(node, env)
+}
+
+func evalTailBlockStatement(
+	block *ast.BlockStatement,
+	env *object.Environment,
+	result bool,
+) object.Object {
+	var obj object.Object
+
+	for i, stmt := range block.Statements {
+		last := i == len(block.Statements)-1
+		if err := beforeStatement(stmt, env); err != nil {
+			return err
+		}
+		obj = evalTail(stmt, env, result && last)
+
+		if obj != nil {
+			rt := obj.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || isTailCall(obj) {
+				return obj
+			}
+		}
+	}
+
+	return obj
+}
+
+func evalTailIfExpression(
+	ie *ast.IfExpression,
+	env *object.Environment,
+	result bool,
+) object.Object {
+	condition := Eval(ie.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		takeBranch(ie, 0)
+		return evalTailBlockStatement(ie.Consequence, object.NewEnclosedEnvironment(env), result)
+	}
+	takeBranch(ie, 1)
+	if ie.ElseIf != nil {
+		return evalTailIfExpression(ie.ElseIf, env, result)
+	}
+	if ie.Alternative != nil {
+		return evalTailBlockStatement(ie.Alternative, object.NewEnclosedEnvironment(env), result)
+	}
+	return NULL
+}
+
+func isTailCall(obj object.Object) bool {
+	_, ok := obj.(*tailCall)
+	return ok
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"monkey-go/diagnostic"
+	"monkey-go/format"
+	"monkey-go/parser"
+	"os"
+	"strings"
+)
+
+// runFmt implements `monkey fmt [-w] [files...]`. Formatted files are
+// printed to standard output, or written back with -w. Without files,
+// standard input is formatted.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write result to the source file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey fmt [-w] [files...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "monkey fmt: cannot use -w with standard input")
+			return 2
+		}
+		if err := formatStdin(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		if err := formatFile(path, *write); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatStdin() error {
+	src, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	out, err := format.Source(src)
+	if err != nil {
+		return sourceError("<stdin>", src, err)
+	}
+
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func formatFile(path string, write bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	out, err := format.Source(src)
+	if err != nil {
+		return sourceError(path, src, err)
+	}
+
+	if !write {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if bytes.Equal(src, out) {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, info.Mode().Perm())
+}
+
+// sourceError returns err, found in src, the source of the file name, as
+// it is printed: syntax errors with the lines they were found at, other
+// errors after name.
+func sourceError(name string, src []byte, err error) error {
+	var syntax *parser.SyntaxError
+	if !errors.As(err, &syntax) {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	var out strings.Builder
+	printer := &diagnostic.Printer{File: name, Source: string(src), Color: diagnostic.IsTerminal(os.Stderr)}
+	for _, d := range diagnostic.FromParser(syntax.Errors) {
+		printer.Print(&out, d) // nolint
+	}
+	return errors.New(strings.TrimSuffix(out.String(), "\n"))
+}
@@ -0,0 +1,183 @@
+// Package format implements the canonical layout of Monkey source code used
+// by `monkey fmt`.
+package format
+
+import (
+	"bytes"
+	"math"
+	"monkey-go/lexer"
+	"monkey-go/parser"
+	"monkey-go/token"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	indentWidth = 4
+	maxWidth    = 80
+)
+
+// Source formats Monkey source code.
+//
+// The output is indented with four spaces per level, uses one space around
+// binary operators and only the parentheses the grammar needs. Lists, call
+// arguments and hashes that do not fit in 80 columns are broken into one
+// element per line. Comments are kept, and single blank lines between
+// statements are preserved. Formatting the output again returns it
+// unchanged.
+//
+// src must parse without errors; otherwise a *parser.SyntaxError is
+// returned.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &parser.SyntaxError{Errors: p.ErrorList()}
+	}
+
+	pr := &printer{
+		lineStart: true,
+		lines:     strings.Split(string(src), "\n"),
+		comments:  l.Comments(),
+	}
+	pr.program(program)
+
+	return pr.buf.Bytes(), nil
+}
+
+// endOfFile is a position after every token of a file.
+var endOfFile = token.Token{Type: token.EOF, Line: math.MaxInt}
+
+type printer struct {
+	buf       bytes.Buffer
+	indent    int
+	column    int  // runes written on the current line, including indentation
+	lineStart bool // nothing written on the current line yet
+	first     bool // no line written yet in the current block or list
+
+	// flat is set while trying to print a node on a single line; failed
+	// records that the node needs several lines
+	flat   bool
+	failed bool
+
+	lines    []string      // source lines, to find blank lines and trailing comments
+	comments []token.Token // comments not printed yet, in source order
+}
+
+func (p *printer) write(s string) {
+	if p.lineStart {
+		p.buf.WriteString(strings.Repeat(" ", p.indent*indentWidth))
+		p.column = p.indent * indentWidth
+		p.lineStart = false
+	}
+	p.buf.WriteString(s)
+	p.column += utf8.RuneCountInString(s)
+}
+
+func (p *printer) newline() {
+	if p.flat {
+		p.failed = true
+		return
+	}
+	p.buf.WriteByte('\n')
+	p.column = 0
+	p.lineStart = true
+}
+
+// item starts a new line for a statement, list element or comment starting
+// at the token start. A blank line in front of it in the source is kept,
+// unless it is the first thing in its block.
+func (p *printer) item(start token.Token) {
+	if !p.lineStart {
+		p.newline()
+	}
+	if !p.first && !p.afterCode(start) && p.blankLine(start.Line-1) {
+		p.buf.WriteByte('\n')
+	}
+	p.first = false
+}
+
+func (p *printer) blankLine(line int) bool {
+	if line < 1 || line > len(p.lines) {
+		return false
+	}
+	return strings.TrimSpace(p.lines[line-1]) == ""
+}
+
+// open starts an indented block or list and returns the state to hand to
+// close at its end.
+func (p *printer) open(s string) bool {
+	p.write(s)
+	p.indent++
+	first := p.first
+	p.first = true
+	return first
+}
+
+func (p *printer) close(s string, first bool) {
+	p.indent--
+	if !p.lineStart {
+		p.newline()
+	}
+	p.write(s)
+	p.first = first
+}
+
+// flush prints the comments that come before limit. A comment that follows
+// code on its source line stays at the end of the current line if there is
+// one; all others get a line of their own.
+func (p *printer) flush(limit token.Token) {
+	for len(p.comments) > 0 && before(p.comments[0], limit) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if p.afterCode(c) && !p.lineStart {
+			p.write(" " + c.Literal)
+		} else {
+			p.item(c)
+			p.write(c.Literal)
+		}
+		p.newline()
+	}
+}
+
+// commentBefore reports whether a comment that has not been printed yet
+// comes before limit.
+func (p *printer) commentBefore(limit token.Token) bool {
+	return len(p.comments) > 0 && before(p.comments[0], limit)
+}
+
+// afterCode reports whether tok follows other code on its source line.
+func (p *printer) afterCode(tok token.Token) bool {
+	if tok.Line < 1 || tok.Line > len(p.lines) {
+		return false
+	}
+	line := []rune(p.lines[tok.Line-1])
+	if tok.Column-1 > len(line) {
+		return false
+	}
+	return strings.TrimSpace(string(line[:tok.Column-1])) != ""
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// tryFlat prints with fn on a single line and returns the text and whether
+// that was possible. Nothing is written to p.
+func (p *printer) tryFlat(fn func(p *printer)) (string, bool) {
+	flat := &printer{flat: true}
+	fn(flat)
+	return flat.buf.String(), !flat.failed
+}
+
+// fits reports whether s can be written on the current line.
+func (p *printer) fits(s string) bool {
+	column := p.column
+	if p.lineStart {
+		column = p.indent * indentWidth
+	}
+	return column+utf8.RuneCountInString(s) <= maxWidth
+}
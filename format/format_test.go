@@ -0,0 +1,217 @@
+package format
+
+import (
+	"monkey-go/ast"
+	"monkey-go/lexer"
+	"monkey-go/parser"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// spacing and semicolons
+		{"let x=1+2*3", "let x = 1 + 2 * 3;\n"},
+		{"let x = 1;let y = 2;", "let x = 1;\nlet y = 2;\n"},
+		{"const  z=0xff+1_000", "const z = 0xff + 1_000;\n"},
+		{"puts( \"a\" ,1 )", "puts(\"a\", 1);\n"},
+		{"return  x;", "return x;\n"},
+		{"x = y = 3", "x = y = 3;\n"},
+		{"let n=null", "let n = null;\n"},
+		{"!-x; -(-x); ~(a & b)", "!-x;\n-(-x);\n~(a & b);\n"},
+		{"", ""},
+
+		// only the parentheses that are needed
+		{"(1 + 2) * 3", "(1 + 2) * 3;\n"},
+		{"((1 * 2)) + 3", "1 * 2 + 3;\n"},
+		{"a - (b - c); (a - b) - c", "a - (b - c);\na - b - c;\n"},
+		{"2 ** (3 ** 2); (2 ** 3) ** 2", "2 ** 3 ** 2;\n(2 ** 3) ** 2;\n"},
+		{"-2 ** 2; (-2) ** 2; 2 ** -1", "-2 ** 2;\n(-2) ** 2;\n2 ** -1;\n"},
+		{"a & b | c ^ d << 1", "a & b | c ^ d << 1;\n"},
+		{"(a | b) & c", "(a | b) & c;\n"},
+		{"a ? b : (c ? d : e)", "a ? b : c ? d : e;\n"},
+		{"(a ? b : c) ? d : e", "(a ? b : c) ? d : e;\n"},
+		{"x = (a ? b : c)", "x = a ? b : c;\n"},
+		{"(a + b)(c); (f(1))[0]; (-a)[0]; -(a[0])", "(a + b)(c);\nf(1)[0];\n(-a)[0];\n-a[0];\n"},
+		{"fn(x) { x }(5)", "(fn(x) { x })(5);\n"},
+
+		// blocks
+		{"let add=fn(a,b){a+b};", "let add = fn(a, b) { a + b };\n"},
+		{"let f = fn() {}", "let f = fn() {};\n"},
+		{
+			"let f = fn(x) { let y = x; y }",
+			"let f = fn(x) {\n    let y = x;\n    y\n};\n",
+		},
+		{
+			"if (x < 2) { return x; } else { x * 2 }",
+			"if (x < 2) {\n    return x;\n} else {\n    x * 2\n}\n",
+		},
+		{
+			"if (a) { 1 } else if (b) { 2 } else { 3 }",
+			"if (a) {\n    1\n} else if (b) {\n    2\n} else {\n    3\n}\n",
+		},
+		// an if followed by a statement that could continue it keeps its ;
+		{
+			"if (a) { 1 }; (b + 1) * 2",
+			"if (a) {\n    1\n};\n(b + 1) * 2;\n",
+		},
+		{
+			"if (a) { 1 }; [b]",
+			"if (a) {\n    1\n};\n[b];\n",
+		},
+		{
+			"if (a) { 1 }; let b = 2",
+			"if (a) {\n    1\n}\nlet b = 2;\n",
+		},
+
+		// match and patterns
+		{
+			`match (s) { {"type": "circle", r} => r, [x, y] if x == y => x, -1 => 0, _ => 1 }`,
+			"match (s) {\n" +
+				"    {\"type\": \"circle\", r} => r,\n" +
+				"    [x, y] if x == y => x,\n" +
+				"    -1 => 0,\n" +
+				"    _ => 1,\n" +
+				"}\n",
+		},
+		{
+			`let [a,b,...rest]=xs; let {name, "age": years, k: v} = h`,
+			"let [a, b, ...rest] = xs;\nlet {name, \"age\": years, k: v} = h;\n",
+		},
+
+		// lists
+		{`let h = {"b": 1, "a": [1,2]}`, "let h = {\"b\": 1, \"a\": [1, 2]};\n"},
+		{"let e = [ ]; let h = { }", "let e = [];\nlet h = {};\n"},
+		{
+			`let long = ["aaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbb", "cccccccccccccccccccc", "dd"]`,
+			"let long = [\n" +
+				"    \"aaaaaaaaaaaaaaaaaaaa\",\n" +
+				"    \"bbbbbbbbbbbbbbbbbbbb\",\n" +
+				"    \"cccccccccccccccccccc\",\n" +
+				"    \"dd\"\n" +
+				"];\n",
+		},
+		{
+			`let h = {"name": "monkey", "version": 1, "authors": ["alice", "bob", "carol"], "x": 1}`,
+			"let h = {\n" +
+				"    \"name\": \"monkey\",\n" +
+				"    \"version\": 1,\n" +
+				"    \"authors\": [\"alice\", \"bob\", \"carol\"],\n" +
+				"    \"x\": 1\n" +
+				"};\n",
+		},
+		{
+			"map(xs, fn(s) { let t = s + s; t })",
+			"map(xs, fn(s) {\n    let t = s + s;\n    t\n});\n",
+		},
+		{
+			"reduce([1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18], 0, fn(a, x) { a + x })",
+			"reduce([1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18], 0, fn(a, x) {\n" +
+				"    a + x\n" +
+				"});\n",
+		},
+
+		// comments and blank lines
+		{
+			"// header\n\n\n\nlet x = 1; // one\n// about y\nlet y = 2;\n\n\nlet z = 3; let w = 4;\n// end",
+			"// header\n\nlet x = 1; // one\n// about y\nlet y = 2;\n\nlet z = 3;\nlet w = 4;\n// end\n",
+		},
+		{
+			"let f = fn() {\n  // todo\n};",
+			"let f = fn() {\n    // todo\n};\n",
+		},
+		{
+			"let g = fn() { // start\n\n  x; // x\n\n  y\n  // end\n}",
+			"let g = fn() { // start\n    x; // x\n\n    y\n    // end\n};\n",
+		},
+		{
+			"foo(1, // one\n  2);",
+			"foo(\n    1, // one\n    2\n);\n",
+		},
+		{
+			"let m = {\n \"a\": 1, // first\n // about b\n \"b\": 2\n};",
+			"let m = {\n    \"a\": 1, // first\n    // about b\n    \"b\": 2\n};\n",
+		},
+		{
+			"match (x) {\n// zero\n0 => \"zero\", // arm\n_ => \"other\"\n// last\n}",
+			"match (x) {\n    // zero\n    0 => \"zero\", // arm\n    _ => \"other\",\n    // last\n}\n",
+		},
+		// a comment inside an expression kept on one line moves to its end
+		{
+			"let x = 1 + // one\n 2;",
+			"let x = 1 + 2; // one\n",
+		},
+	}
+
+	for _, tt := range tests {
+		out, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("Source(%q) returned error: %s", tt.input, err)
+			continue
+		}
+		if string(out) != tt.expected {
+			t.Errorf("Source(%q) wrong.\nexpected:\n%s\ngot:\n%s", tt.input, tt.expected, out)
+			continue
+		}
+
+		again, err := Source(out)
+		if err != nil {
+			t.Errorf("formatted source does not parse: %s\n%s", err, out)
+			continue
+		}
+		if string(again) != string(out) {
+			t.Errorf("Source is not idempotent.\nfirst:\n%s\nsecond:\n%s", out, again)
+		}
+
+		if got, want := parse(t, string(out)), parse(t, tt.input); got != want {
+			t.Errorf("formatting changed the program.\nexpected: %s\ngot:      %s", want, got)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 5;\nlet x = );"))
+	if err == nil {
+		t.Fatalf("expected an error for invalid source")
+	}
+
+	expected := "expected next token to be IDENT, got = instead."
+	if err.Error() != expected+"\nno prefix parse function for ) found" {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+	// the positions are kept for the renderer
+	syntax, ok := err.(*parser.SyntaxError)
+	if !ok || len(syntax.Errors) != 2 || syntax.Errors[1].Line != 2 || syntax.Errors[1].Column != 9 {
+		t.Errorf("wrong syntax errors. got=%#v", err)
+	}
+}
+
+// parse returns the debug form of the program in input, with the pairs of
+// hash literals sorted so that it does not depend on map order.
+func parse(t *testing.T, input string) string {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	sorted := ast.Modify(program, func(node ast.Node) ast.Node {
+		hash, ok := node.(*ast.HashMapLiteral)
+		if !ok {
+			return node
+		}
+		var pairs []string
+		for key, value := range hash.Pairs {
+			pairs = append(pairs, key.String()+":"+value.String())
+		}
+		sort.Strings(pairs)
+		return &ast.Identifier{Value: "{" + strings.Join(pairs, ", ") + "}"}
+	})
+	return sorted.String()
+}
@@ -0,0 +1,471 @@
+package format
+
+import (
+	"monkey-go/ast"
+	"monkey-go/parser"
+	"monkey-go/token"
+	"sort"
+	"strconv"
+)
+
+// atom is the precedence of expressions that never need parentheses.
+const atom = parser.INDEX + 1
+
+func (p *printer) program(program *ast.Program) {
+	p.first = true
+	p.statements(program.Statements, endOfFile, false)
+	if !p.lineStart {
+		p.newline()
+	}
+}
+
+// statements prints a statement list ending at the token end, the closing
+// brace of a block or the end of the file, with the comments in it.
+func (p *printer) statements(stmts []ast.Statement, end token.Token, inBlock bool) {
+	for i, stmt := range stmts {
+		start := ast.Start(stmt)
+		p.flush(start)
+		p.item(start)
+
+		var next ast.Statement
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+		}
+		p.statement(stmt)
+		if p.needsSemicolon(stmt, next, inBlock) {
+			p.write(";")
+		}
+	}
+	p.flush(end)
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if stmt.IsConst() {
+			p.write("const ")
+		} else {
+			p.write("let ")
+		}
+		p.pattern(stmt.Target())
+		p.write(" = ")
+		p.expr(stmt.Value)
+
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expr(stmt.ReturnValue)
+
+	case *ast.ExpressionStatement:
+		p.expr(stmt.Expression)
+	}
+}
+
+// needsSemicolon decides whether stmt is terminated with a semicolon. Let
+// and return statements always are. Expression statements are, except for
+// the value at the end of a block and for if and match expressions, unless
+// the next statement would otherwise be read as a continuation of them,
+// e.g. as call arguments.
+func (p *printer) needsSemicolon(stmt, next ast.Statement, inBlock bool) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return true
+	}
+	if next == nil {
+		return !inBlock && !endsWithBlock(es.Expression)
+	}
+	if !endsWithBlock(es.Expression) {
+		return true
+	}
+
+	text, _ := p.tryFlat(func(p *printer) { p.statement(next) })
+	return text != "" && (text[0] == '(' || text[0] == '[' || text[0] == '-')
+}
+
+func endsWithBlock(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.IfExpression, *ast.MatchExpression:
+		return true
+	}
+	return false
+}
+
+// blockLike reports whether e is written with braces. Such expressions are
+// parenthesized when they are an operand, to make that easier to see.
+func blockLike(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.IfExpression, *ast.MatchExpression, *ast.FunctionLiteral, *ast.MacroLiteral:
+		return true
+	}
+	return false
+}
+
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(e.Operator))
+	case *ast.ConditionalExpression:
+		return parser.TERNARY
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	}
+	return atom
+}
+
+func rightAssociative(operator string) bool {
+	return operator == "=" || operator == "**"
+}
+
+// operand prints e, in parentheses if parens is set.
+func (p *printer) operand(e ast.Expression, parens bool) {
+	if parens {
+		p.write("(")
+		p.expr(e)
+		p.write(")")
+		return
+	}
+	p.expr(e)
+}
+
+func (p *printer) expr(e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+
+	case *ast.IntegerLiteral:
+		// keep the spelling of the literal, e.g. 0xff or 1_000
+		if e.Token.Type == token.INT && e.Token.Literal != "" {
+			p.write(e.Token.Literal)
+		} else {
+			p.write(strconv.FormatInt(e.Value, 10))
+		}
+
+	case *ast.StringLiteral:
+		p.write(`"` + e.Value + `"`)
+
+	case *ast.Boolean:
+		p.write(strconv.FormatBool(e.Value))
+
+	case *ast.NullLiteral:
+		p.write("null")
+
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		// -(-x) rather than --x
+		nested, _ := e.Right.(*ast.PrefixExpression)
+		p.operand(e.Right, precedence(e.Right) < parser.PREFIX ||
+			nested != nil && nested.Operator == e.Operator && e.Operator == "-")
+
+	case *ast.InfixExpression:
+		prec := precedence(e)
+		right := rightAssociative(e.Operator)
+
+		lp := precedence(e.Left)
+		p.operand(e.Left, lp < prec || lp == prec && right || blockLike(e.Left))
+
+		p.write(" " + e.Operator + " ")
+
+		// a prefix expression on the right is complete in itself
+		_, prefix := e.Right.(*ast.PrefixExpression)
+		rp := precedence(e.Right)
+		p.operand(e.Right, !prefix && (rp < prec || rp == prec && !right))
+
+	case *ast.ConditionalExpression:
+		cp := precedence(e.Condition)
+		p.operand(e.Condition, cp <= parser.TERNARY || blockLike(e.Condition))
+		p.write(" ? ")
+		p.expr(e.Consequence)
+		p.write(" : ")
+		p.operand(e.Alternative, precedence(e.Alternative) < parser.TERNARY)
+
+	case *ast.IfExpression:
+		p.ifExpression(e)
+
+	case *ast.MatchExpression:
+		p.matchExpression(e)
+
+	case *ast.FunctionLiteral:
+		p.write("fn")
+		p.parameters(e.Parameters)
+		p.write(" ")
+		p.block(e.Body, true)
+
+	case *ast.MacroLiteral:
+		p.write("macro")
+		p.parameters(e.Parameters)
+		p.write(" ")
+		p.block(e.Body, true)
+
+	case *ast.CallExpression:
+		p.operand(e.Function, precedence(e.Function) < parser.CALL || blockLike(e.Function))
+		p.list("(", ")", p.expressions(e.Arguments))
+
+	case *ast.IndexExpression:
+		p.operand(e.Left, precedence(e.Left) < parser.CALL || blockLike(e.Left))
+		p.write("[")
+		p.expr(e.Index)
+		p.write("]")
+
+	case *ast.ArrayLiteral:
+		p.list("[", "]", p.expressions(e.Elements))
+
+	case *ast.HashMapLiteral:
+		p.list("{", "}", p.pairs(e))
+	}
+}
+
+func (p *printer) ifExpression(e *ast.IfExpression) {
+	p.write("if (")
+	p.expr(e.Condition)
+	p.write(") ")
+	p.block(e.Consequence, false)
+
+	if e.ElseIf != nil {
+		p.write(" else ")
+		p.ifExpression(e.ElseIf)
+		return
+	}
+	if e.Alternative == nil {
+		return
+	}
+	p.write(" else ")
+	p.block(e.Alternative, false)
+}
+
+func (p *printer) matchExpression(e *ast.MatchExpression) {
+	p.write("match (")
+	p.expr(e.Subject)
+	p.write(") ")
+
+	if len(e.Arms) == 0 && !p.commentBefore(e.Rbrace) {
+		p.write("{}")
+		return
+	}
+
+	first := p.open("{")
+	for _, arm := range e.Arms {
+		start := ast.Start(arm.Pattern)
+		p.flush(start)
+		p.item(start)
+
+		p.pattern(arm.Pattern)
+		if arm.Guard != nil {
+			p.write(" if ")
+			p.expr(arm.Guard)
+		}
+		p.write(" => ")
+		p.expr(arm.Body)
+		p.write(",")
+	}
+	p.flush(e.Rbrace)
+	p.close("}", first)
+}
+
+// block prints a block statement. A block holding a single expression is
+// kept on one line, as in fn(x) { x * 2 }, if inline is set and it fits.
+func (p *printer) block(b *ast.BlockStatement, inline bool) {
+	single := false
+	if len(b.Statements) == 1 {
+		_, single = b.Statements[0].(*ast.ExpressionStatement)
+	}
+
+	if p.flat {
+		switch {
+		case len(b.Statements) == 0:
+			p.write("{}")
+		case single:
+			p.write("{ ")
+			p.statement(b.Statements[0])
+			p.write(" }")
+		default:
+			p.failed = true
+		}
+		return
+	}
+
+	if !p.commentBefore(b.Rbrace) {
+		if len(b.Statements) == 0 {
+			p.write("{}")
+			return
+		}
+		if inline && single {
+			text, ok := p.tryFlat(func(p *printer) { p.block(b, true) })
+			if ok && p.fits(text) {
+				p.write(text)
+				return
+			}
+		}
+	}
+
+	first := p.open("{")
+	p.statements(b.Statements, b.Rbrace, true)
+	p.close("}", first)
+}
+
+func (p *printer) parameters(params []ast.Expression) {
+	p.write("(")
+	for i, param := range params {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.pattern(param)
+	}
+	p.write(")")
+}
+
+func (p *printer) pattern(e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.ArrayPattern:
+		p.write("[")
+		for i, element := range e.Elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.pattern(element)
+		}
+		if e.Rest != nil {
+			if len(e.Elements) > 0 {
+				p.write(", ")
+			}
+			p.write("..." + e.Rest.Value)
+		}
+		p.write("]")
+
+	case *ast.HashPattern:
+		p.write("{")
+		for i, pair := range e.Pairs {
+			if i > 0 {
+				p.write(", ")
+			}
+			key, _ := pair.Key.(*ast.StringLiteral)
+			if key == nil || key.Token.Type != token.IDENT {
+				p.expr(pair.Key)
+				p.write(": ")
+				p.pattern(pair.Value)
+				continue
+			}
+			// a bare name key, {name} or {name: pattern}
+			p.write(key.Value)
+			if ident, ok := pair.Value.(*ast.Identifier); !ok || ident.Value != key.Value {
+				p.write(": ")
+				p.pattern(pair.Value)
+			}
+		}
+		p.write("}")
+
+	default:
+		p.expr(e)
+	}
+}
+
+// element is one entry of a list printed by list.
+type element struct {
+	start token.Token
+	print func(p *printer)
+	hug   bool // a function literal that may open on the line of the list
+}
+
+func (p *printer) expressions(exps []ast.Expression) []element {
+	elements := make([]element, len(exps))
+	for i, e := range exps {
+		e := e
+		_, fn := e.(*ast.FunctionLiteral)
+		elements[i] = element{
+			start: ast.Start(e),
+			print: func(p *printer) { p.expr(e) },
+			hug:   fn,
+		}
+	}
+	return elements
+}
+
+// pairs returns the entries of a hash literal in source order.
+func (p *printer) pairs(hash *ast.HashMapLiteral) []element {
+	keys := make([]ast.Expression, 0, len(hash.Pairs))
+	for key := range hash.Pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := ast.Start(keys[i]), ast.Start(keys[j])
+		if a.Line != b.Line || a.Column != b.Column {
+			return before(a, b)
+		}
+		return keys[i].String() < keys[j].String()
+	})
+
+	elements := make([]element, len(keys))
+	for i, key := range keys {
+		key, value := key, hash.Pairs[key]
+		elements[i] = element{
+			start: ast.Start(key),
+			print: func(p *printer) {
+				p.expr(key)
+				p.write(": ")
+				p.expr(value)
+			},
+		}
+	}
+	return elements
+}
+
+// list prints elements separated by commas between open and close. They are
+// kept on one line if they fit and there are no comments among them.
+// Otherwise a trailing function literal may start on the line of the list,
+// as in map(xs, fn(x) {, or every element gets a line of its own.
+func (p *printer) list(open, close string, elements []element) {
+	if len(elements) == 0 {
+		p.write(open + close)
+		return
+	}
+
+	if p.flat {
+		p.write(open)
+		for i, e := range elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			e.print(p)
+		}
+		p.write(close)
+		return
+	}
+
+	last := elements[len(elements)-1]
+	if !p.commentBefore(last.start) {
+		text, ok := p.tryFlat(func(p *printer) { p.list(open, close, elements) })
+		if ok && p.fits(text) {
+			p.write(text)
+			return
+		}
+
+		if last.hug {
+			head, ok := p.tryFlat(func(p *printer) {
+				p.write(open)
+				for _, e := range elements[:len(elements)-1] {
+					e.print(p)
+					p.write(", ")
+				}
+			})
+			if ok && p.fits(head) {
+				p.write(head)
+				last.print(p)
+				p.write(close)
+				return
+			}
+		}
+	}
+
+	first := p.open(open)
+	for i, e := range elements {
+		p.flush(e.start)
+		p.item(e.start)
+		e.print(p)
+		if i < len(elements)-1 {
+			p.write(",")
+		}
+	}
+	p.close(close, first)
+}
@@ -0,0 +1,69 @@
+// Package framing reads and writes messages framed by a Content-Length
+// header, the base protocol shared by the Language Server Protocol and the
+// Debug Adapter Protocol.
+package framing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxLength is the largest Content-Length Read accepts, far more than any
+// request of an editor needs.
+const MaxLength = 64 << 20
+
+// Read reads the body of one message. Headers other than Content-Length
+// are ignored. io.EOF is returned if r ends before a message starts, and
+// io.ErrUnexpectedEOF if it ends within the body.
+func Read(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+			if length > MaxLength {
+				return nil, fmt.Errorf("Content-Length %d exceeds the limit of %d bytes", length, MaxLength)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	// the body grows as it arrives rather than being allocated up front
+	body, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return body, nil
+}
+
+// Write writes body with a Content-Length header.
+func Write(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,66 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{"Content-Length: 2\r\n\r\n{}", "{}", ""},
+		{"content-length: 3\r\nContent-Type: x\r\n\r\nabcdef", "abc", ""},
+		{"Content-Length: 0\r\n\r\n", "", ""},
+		{"", "", "EOF"},
+		{"Content-Length: 5\r\n\r\nab", "", "unexpected EOF"},
+		{"Content-Type: x\r\n\r\n", "", "missing Content-Length header"},
+		{"Content-Length\r\n\r\n", "", `malformed header "Content-Length"`},
+		{"Content-Length: -1\r\n\r\n", "", `invalid Content-Length " -1"`},
+		{"Content-Length: x\r\n\r\n", "", `invalid Content-Length " x"`},
+		{"Content-Length: 99999999999999999999\r\n\r\n", "", `invalid Content-Length " 99999999999999999999"`},
+		{"Content-Length: 67108865\r\n\r\n", "", "Content-Length 67108865 exceeds the limit of 67108864 bytes"},
+	}
+
+	for _, tt := range tests {
+		body, err := Read(bufio.NewReader(strings.NewReader(tt.input)))
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: wrong error. want=%q, got=%v", tt.input, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if string(body) != tt.expected {
+			t.Errorf("%q: wrong body. want=%q, got=%q", tt.input, tt.expected, body)
+		}
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	for _, body := range []string{`{"id":1}`, "", "こんにちは"} {
+		if err := Write(&buf, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := bufio.NewReader(&buf)
+	for _, expected := range []string{`{"id":1}`, "", "こんにちは"} {
+		body, err := Read(r)
+		if err != nil || string(body) != expected {
+			t.Errorf("wrong message. want=%q, got=%q, %v", expected, body, err)
+		}
+	}
+	if _, err := Read(r); err != io.EOF {
+		t.Errorf("expected io.EOF after the last message. got=%v", err)
+	}
+}
@@ -0,0 +1,62 @@
+// Package suggest finds the name that was meant when a misspelt one is
+// used, for the hints of error messages.
+package suggest
+
+// Closest returns the candidate closest to name, a misspelt identifier, or
+// "" if none is close enough to be what was meant: the number of edits
+// needed to turn it into name must be at most a third of its length, or 1,
+// and less than the length of name.
+func Closest(name string, candidates []string) string {
+	best, bestDistance := "", 0
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+		d := distance(name, c)
+		limit := len([]rune(c)) / 3
+		if limit < 1 {
+			limit = 1
+		}
+		if d > limit || d >= len([]rune(name)) {
+			continue
+		}
+		if best == "" || d < bestDistance || d == bestDistance && c < best {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// distance returns the number of runes to insert, delete or replace, or
+// pairs of adjacent runes to swap, to turn a into b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = d[i-1][j-1] + cost
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,27 @@
+package suggest
+
+import "testing"
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"len", "length", "print", "push", "first", "x"}
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"lenght", "length"},
+		{"pirnt", "print"},
+		{"lne", "len"},
+		{"fisrt", "first"},
+		{"y", ""},
+		{"xs", "x"},
+		{"pusher", ""},
+		{"len", ""},
+		{"completely", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Closest(tt.name, candidates); got != tt.expected {
+			t.Errorf("Closest(%q) wrong. want=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package lexer
 
 import (
+	"fmt"
 	"monkey-go/token"
+	"strings"
+	"unicode"
 )
 
 type Lexer struct {
@@ -9,16 +12,40 @@ type Lexer struct {
 	position     int  // 入力における現在の位置
 	readPosition int  // 入力における次の位置
 	r            rune // 現在見ている文字
+	line         int  // 現在見ている文字の行 (1-based)
+	column       int  // 現在見ている文字の列 (1-based, rune単位)
+
+	errors   []string
+	comments []token.Token
 }
 
 func New(input string) *Lexer {
 	ir := []rune(input)
-	l := &Lexer{input: ir}
+	l := &Lexer{input: ir, line: 1}
 	l.readRune()
 	return l
 }
 
+// Errors returns the lexical errors found so far. Every ILLEGAL token
+// produced by NextToken has a corresponding entry.
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+
+// Comments returns the `//` comments skipped so far as token.COMMENT tokens
+// in source order. The parser never sees them; tools such as the formatter
+// use them to put comments back into the output.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 func (l *Lexer) readRune() {
+	if l.r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
 	if l.readPosition >= len(l.input) { // input = Null or 終端に達した場合
 		l.r = 0
 	} else {
@@ -29,10 +56,17 @@ func (l *Lexer) readRune() {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
 
+	line, column := l.line, l.column
+	tok := l.nextToken()
+	tok.Line, tok.Column = line, column
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
+	var tok token.Token
+
 	switch l.r {
 	case '=':
 		if l.peekRune() == '=' {
@@ -40,6 +74,11 @@ func (l *Lexer) NextToken() token.Token {
 			l.readRune()
 			literal := string(r) + string(l.r)
 			tok = token.Token{Type: token.EQ, Literal: literal}
+		} else if l.peekRune() == '>' {
+			r := l.r
+			l.readRune()
+			literal := string(r) + string(l.r)
+			tok = token.Token{Type: token.ARROW, Literal: literal}
 		} else {
 			tok = newToken(token.ASSIGN, l.r)
 		}
@@ -59,15 +98,55 @@ func (l *Lexer) NextToken() token.Token {
 	case '/':
 		tok = newToken(token.SLASH, l.r)
 	case '*':
-		tok = newToken(token.ASTERISK, l.r)
+		if l.peekRune() == '*' {
+			r := l.r
+			l.readRune()
+			literal := string(r) + string(l.r)
+			tok = token.Token{Type: token.POWER, Literal: literal}
+		} else {
+			tok = newToken(token.ASTERISK, l.r)
+		}
 	case '<':
-		tok = newToken(token.LT, l.r)
+		if l.peekRune() == '<' {
+			r := l.r
+			l.readRune()
+			literal := string(r) + string(l.r)
+			tok = token.Token{Type: token.LSHIFT, Literal: literal}
+		} else {
+			tok = newToken(token.LT, l.r)
+		}
 	case '>':
-		tok = newToken(token.GT, l.r)
+		if l.peekRune() == '>' {
+			r := l.r
+			l.readRune()
+			literal := string(r) + string(l.r)
+			tok = token.Token{Type: token.RSHIFT, Literal: literal}
+		} else {
+			tok = newToken(token.GT, l.r)
+		}
+	case '&':
+		tok = newToken(token.AMPERSAND, l.r)
+	case '|':
+		tok = newToken(token.PIPE, l.r)
+	case '^':
+		tok = newToken(token.CARET, l.r)
+	case '~':
+		tok = newToken(token.TILDE, l.r)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.r)
 	case ':':
 		tok = newToken(token.COLON, l.r)
+	case '?':
+		tok = newToken(token.QUESTION, l.r)
+	case '.':
+		if l.peekRune() == '.' && l.peekRuneAt(2) == '.' {
+			l.readRune()
+			l.readRune()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.ILLEGAL, l.r)
+			l.errors = append(l.errors, fmt.Sprintf("unexpected character %q", l.r))
+		}
 	case ',':
 		tok = newToken(token.COMMA, l.r)
 	case '(':
@@ -94,11 +173,10 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.r) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
-			return tok
+			return l.readNumber()
 		} else {
 			tok = newToken(token.ILLEGAL, l.r)
+			l.errors = append(l.errors, fmt.Sprintf("unexpected character %q", l.r))
 		}
 	}
 
@@ -106,30 +184,107 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// skipWhitespace skips whitespace and `//` comments, which run to the end
+// of the line.
 func (l *Lexer) skipWhitespace() {
-	for l.r == ' ' || l.r == '\t' || l.r == '\n' || l.r == '\r' {
+	for {
+		switch {
+		case l.r == ' ' || l.r == '\t' || l.r == '\n' || l.r == '\r':
+			l.readRune()
+		case l.r == '/' && l.peekRune() == '/':
+			l.readComment()
+		default:
+			return
+		}
+	}
+}
+
+func (l *Lexer) readComment() {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	position := l.position
+	for l.r != '\n' && l.r != 0 {
 		l.readRune()
 	}
+	tok.Literal = strings.TrimRight(string(l.input[position:l.position]), " \t\r")
+	l.comments = append(l.comments, tok)
 }
 
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.r) {
+	for isLetter(l.r) || unicode.IsDigit(l.r) {
 		l.readRune()
 	}
 	return string(l.input[position:l.position])
 }
 
+// isLetter reports whether r can start an identifier: any Unicode letter or
+// an underscore. Identifiers may continue with Unicode digits as well.
 func isLetter(r rune) bool {
-	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_'
+	return unicode.IsLetter(r) || r == '_'
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer literal: decimal digits, or digits in the base
+// selected by a 0x, 0o or 0b prefix, optionally grouped with underscores as
+// in 1_000_000. A malformed literal is returned as an ILLEGAL token.
+func (l *Lexer) readNumber() token.Token {
 	position := l.position
-	for isDigit(l.r) {
+	// consume trailing letters too so that 0xZZ or 12ab are reported as a
+	// whole instead of lexing as a number followed by an identifier
+	for isLetter(l.r) || isDigit(l.r) {
 		l.readRune()
 	}
-	return string(l.input[position:l.position])
+	literal := string(l.input[position:l.position])
+
+	if msg := checkNumber(literal); msg != "" {
+		l.errors = append(l.errors, fmt.Sprintf("invalid integer literal %q: %s", literal, msg))
+		return token.Token{Type: token.ILLEGAL, Literal: literal}
+	}
+	return token.Token{Type: token.INT, Literal: literal}
+}
+
+var numberBases = map[byte]struct {
+	name   string
+	digits string
+}{
+	'x': {"hexadecimal", "0123456789abcdefABCDEF"},
+	'o': {"octal", "01234567"},
+	'b': {"binary", "01"},
+}
+
+// checkNumber validates an integer literal and describes the first problem
+// found, or returns "" if the literal is well formed.
+func checkNumber(literal string) string {
+	name, digits := "decimal", "0123456789"
+	body := literal
+
+	if len(literal) >= 2 && literal[0] == '0' {
+		if base, ok := numberBases[literal[1]|0x20]; ok { // lower-case the prefix letter
+			name, digits = base.name, base.digits
+			body = literal[2:]
+			if strings.Trim(body, "_") == "" {
+				return fmt.Sprintf("%s literal has no digits", name)
+			}
+		} else if (isDigit(rune(literal[1])) || literal[1] == '_') && strings.Trim(literal, "0_") != "" {
+			return "leading zeros are not allowed in decimal literals, use 0o for octal"
+		}
+	}
+
+	prevDigit := body != literal // an underscore may directly follow the prefix
+	for i, r := range body {
+		switch {
+		case r == '_':
+			if !prevDigit || i == len(body)-1 {
+				return "'_' must separate successive digits"
+			}
+			prevDigit = false
+		case strings.ContainsRune(digits, r):
+			prevDigit = true
+		default:
+			return fmt.Sprintf("invalid digit %q in %s literal", r, name)
+		}
+	}
+
+	return ""
 }
 
 func isDigit(r rune) bool {
@@ -140,12 +295,21 @@ func newToken(tokenType token.TokenType, r rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(r)}
 }
 
-func (l *Lexer) peekRune() byte {
+func (l *Lexer) peekRune() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return byte(l.input[l.readPosition])
+		return l.input[l.readPosition]
+	}
+}
+
+// peekRuneAt returns the rune n positions ahead of the current one.
+func (l *Lexer) peekRuneAt(n int) rune {
+	pos := l.position + n
+	if pos >= len(l.input) {
+		return 0
 	}
+	return l.input[pos]
 }
 
 func (l *Lexer) readString() string {
@@ -31,6 +31,10 @@ if (5<10){
 "foo bar"
 [1, 2];
 {"foo": "bar"}
+[a, ...b]
+match (x) { _ => 1 }
+a ? b : c
+a & b | c ^ ~d << 1 >> 2 ** 3 * 4
 `
 
 	tests := []struct {
@@ -135,6 +139,46 @@ if (5<10){
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
 
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "b"},
+		{token.RBRACKET, "]"},
+
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+
+		{token.IDENT, "a"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "b"},
+		{token.COLON, ":"},
+		{token.IDENT, "c"},
+
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.PIPE, "|"},
+		{token.IDENT, "c"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.IDENT, "d"},
+		{token.LSHIFT, "<<"},
+		{token.INT, "1"},
+		{token.RSHIFT, ">>"},
+		{token.INT, "2"},
+		{token.POWER, "**"},
+		{token.INT, "3"},
+		{token.ASTERISK, "*"},
+		{token.INT, "4"},
+
 		{token.EOF, ""},
 	}
 
@@ -153,3 +197,209 @@ if (5<10){
 
 	}
 }
+
+func TestNextTokenUnicode(t *testing.T) {
+	input := `let 変数1 = x1 + y_2z;
+let café = "コーヒー";
+let 점수 = π2 * ٣;
+let _ok = αβγ != Ωmega;
+fn(名前) { 名前 };
+!Ľ
+`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "変数1"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "x1"},
+		{token.PLUS, "+"},
+		{token.IDENT, "y_2z"},
+		{token.SEMICOLON, ";"},
+
+		{token.LET, "let"},
+		{token.IDENT, "café"},
+		{token.ASSIGN, "="},
+		{token.STRING, "コーヒー"},
+		{token.SEMICOLON, ";"},
+
+		{token.LET, "let"},
+		{token.IDENT, "점수"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "π2"},
+		{token.ASTERISK, "*"},
+		{token.ILLEGAL, "٣"}, // identifiers and numbers cannot start with a non-ASCII digit
+		{token.SEMICOLON, ";"},
+
+		{token.LET, "let"},
+		{token.IDENT, "_ok"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "αβγ"},
+		{token.NOT_EQ, "!="},
+		{token.IDENT, "Ωmega"},
+		{token.SEMICOLON, ";"},
+
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "名前"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "名前"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+
+		// U+013D truncated to a byte is '=', so '!' must not become "!="
+		{token.BANG, "!"},
+		{token.IDENT, "Ľ"},
+
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedError   string
+	}{
+		{"0", token.INT, "0", ""},
+		{"1234567890", token.INT, "1234567890", ""},
+		{"1_000_000", token.INT, "1_000_000", ""},
+		{"0xFF", token.INT, "0xFF", ""},
+		{"0XdeadBEEF", token.INT, "0XdeadBEEF", ""},
+		{"0x_FF_FF", token.INT, "0x_FF_FF", ""},
+		{"0o755", token.INT, "0o755", ""},
+		{"0O17", token.INT, "0O17", ""},
+		{"0b1010", token.INT, "0b1010", ""},
+		{"0b_1111_0000", token.INT, "0b_1111_0000", ""},
+		{"00", token.INT, "00", ""},
+		{"0xZZ", token.ILLEGAL, "0xZZ", `invalid integer literal "0xZZ": invalid digit 'Z' in hexadecimal literal`},
+		{"0x", token.ILLEGAL, "0x", `invalid integer literal "0x": hexadecimal literal has no digits`},
+		{"0b_", token.ILLEGAL, "0b_", `invalid integer literal "0b_": binary literal has no digits`},
+		{"0o8", token.ILLEGAL, "0o8", `invalid integer literal "0o8": invalid digit '8' in octal literal`},
+		{"0b102", token.ILLEGAL, "0b102", `invalid integer literal "0b102": invalid digit '2' in binary literal`},
+		{"12ab", token.ILLEGAL, "12ab", `invalid integer literal "12ab": invalid digit 'a' in decimal literal`},
+		{"0a", token.ILLEGAL, "0a", `invalid integer literal "0a": invalid digit 'a' in decimal literal`},
+		{"1__000", token.ILLEGAL, "1__000", `invalid integer literal "1__000": '_' must separate successive digits`},
+		{"1000_", token.ILLEGAL, "1000_", `invalid integer literal "1000_": '_' must separate successive digits`},
+		{"0x__1", token.ILLEGAL, "0x__1", `invalid integer literal "0x__1": '_' must separate successive digits`},
+		{"0755", token.ILLEGAL, "0755", `invalid integer literal "0755": leading zeros are not allowed in decimal literals, use 0o for octal`},
+		{"0_7", token.ILLEGAL, "0_7", `invalid integer literal "0_7": leading zeros are not allowed in decimal literals, use 0o for octal`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Errorf("%q - tokentype wrong. expected=%q, got=%q", tt.input, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("%q - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("%q - literal was not consumed completely. next=%+v", tt.input, next)
+		}
+
+		var got string
+		if errs := l.Errors(); len(errs) > 0 {
+			got = errs[0]
+		}
+		if got != tt.expectedError {
+			t.Errorf("%q - error wrong. expected=%q, got=%q", tt.input, tt.expectedError, got)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + \"あい\" ** 2\n\tfoo"
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 3},
+		{token.PLUS, 2, 5},
+		{token.STRING, 2, 7},
+		{token.POWER, 2, 12},
+		{token.INT, 2, 15},
+		{token.IDENT, 3, 2},
+		{token.EOF, 3, 5},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 1; // trailing  
+x / 2 //
+// last`
+
+	expectedTokens := []token.TokenType{
+		token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.SLASH, token.INT, token.EOF,
+	}
+
+	l := New(input)
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+		if tok.Type != expected {
+			t.Fatalf("tokens[%d] - tokentype wrong. expected=%q, got=%q", i, expected, tok.Type)
+		}
+	}
+
+	expectedComments := []token.Token{
+		{Type: token.COMMENT, Literal: "// leading", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// trailing", Line: 2, Column: 12},
+		{Type: token.COMMENT, Literal: "//", Line: 3, Column: 7},
+		{Type: token.COMMENT, Literal: "// last", Line: 4, Column: 1},
+	}
+
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d (%+v)",
+			len(expectedComments), len(comments), comments)
+	}
+	for i, expected := range expectedComments {
+		if comments[i] != expected {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, expected, comments[i])
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package lint
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/evaluator"
+	"monkey-go/token"
+	"strings"
+)
+
+// binding is a name declared by let, const, a parameter or a match pattern.
+type binding struct {
+	name  string
+	token token.Token // where the name is declared
+	used  bool
+	arity int // parameter count if bound to a function or macro literal, -1 otherwise
+}
+
+// scope mirrors an object.Environment: the program, a function call, an if
+// block or a match arm.
+type scope struct {
+	names map[string]*binding
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: map[string]*binding{}, outer: outer}
+}
+
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.names[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// function is a function or macro body whose check is postponed.
+type function struct {
+	params []ast.Expression
+	body   *ast.BlockStatement
+	scope  *scope
+}
+
+type checker struct {
+	disabled    map[string]bool
+	diagnostics []Diagnostic
+
+	// bindings lists the let and const bindings for the unused rule
+	bindings []*binding
+
+	// A function body runs when the function is called, usually after
+	// the rest of its scope has been declared, as in mutually recursive
+	// functions. Bodies are therefore checked after the statements that
+	// surround them.
+	functions []function
+}
+
+func (c *checker) report(rule string, tok token.Token, format string, a ...any) {
+	if c.disabled[rule] {
+		return
+	}
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Rule:    rule,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *checker) program(program *ast.Program) {
+	c.statements(program.Statements, newScope(nil))
+
+	for len(c.functions) > 0 {
+		fn := c.functions[0]
+		c.functions = c.functions[1:]
+
+		s := newScope(fn.scope)
+		for _, param := range fn.params {
+			c.declare(param, s, false, -1)
+		}
+		c.statements(fn.body.Statements, s)
+	}
+
+	for _, b := range c.bindings {
+		if !b.used && !strings.HasPrefix(b.name, "_") {
+			c.report(RuleUnused, b.token, "%s declared and not used", b.name)
+		}
+	}
+}
+
+func (c *checker) statements(stmts []ast.Statement, s *scope) {
+	returned := false
+
+	for _, stmt := range stmts {
+		if returned {
+			c.report(RuleUnreachable, ast.Start(stmt), "unreachable code")
+			returned = false // report each block once
+		}
+
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			c.expr(stmt.Value, s)
+			c.declare(stmt.Target(), s, true, arity(stmt.Value))
+
+		case *ast.ReturnStatement:
+			c.expr(stmt.ReturnValue, s)
+			returned = true
+
+		case *ast.ExpressionStatement:
+			c.expr(stmt.Expression, s)
+		}
+	}
+}
+
+// declare binds the names in pattern in s. Only let and const bindings are
+// checked for being unused.
+func (c *checker) declare(pattern ast.Expression, s *scope, let bool, fnArity int) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return
+		}
+		if evaluator.IsBuiltin(pattern.Value) {
+			c.report(RuleShadowBuiltin, pattern.Token,
+				"%s shadows the builtin function %s", pattern.Value, pattern.Value)
+		}
+
+		b := &binding{name: pattern.Value, token: pattern.Token, arity: fnArity}
+		s.names[b.name] = b
+		if let {
+			c.bindings = append(c.bindings, b)
+		}
+
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			c.declare(element, s, let, -1)
+		}
+		if pattern.Rest != nil {
+			c.declare(pattern.Rest, s, let, -1)
+		}
+
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			c.declare(pair.Value, s, let, -1)
+		}
+	}
+}
+
+// arity returns the number of parameters if e is a function or macro
+// literal and -1 otherwise.
+func arity(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.FunctionLiteral:
+		return len(e.Parameters)
+	case *ast.MacroLiteral:
+		return len(e.Parameters)
+	}
+	return -1
+}
+
+func (c *checker) expr(e ast.Expression, s *scope) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		if b := c.resolve(e, s); b != nil {
+			b.used = true
+		}
+
+	case *ast.PrefixExpression:
+		c.expr(e.Right, s)
+
+	case *ast.InfixExpression:
+		if ident, ok := e.Left.(*ast.Identifier); ok && e.Operator == token.ASSIGN {
+			// assigning is not reading, and the value may no longer be the
+			// function the name was declared with
+			if b := c.resolve(ident, s); b != nil {
+				b.arity = -1
+			}
+		} else {
+			c.expr(e.Left, s)
+		}
+		c.expr(e.Right, s)
+
+	case *ast.ConditionalExpression:
+		c.expr(e.Condition, s)
+		c.expr(e.Consequence, s)
+		c.expr(e.Alternative, s)
+
+	case *ast.IfExpression:
+		c.expr(e.Condition, s)
+		c.statements(e.Consequence.Statements, newScope(s))
+		if e.ElseIf != nil {
+			c.expr(e.ElseIf, s)
+		}
+		if e.Alternative != nil {
+			c.statements(e.Alternative.Statements, newScope(s))
+		}
+
+	case *ast.MatchExpression:
+		c.expr(e.Subject, s)
+		for _, arm := range e.Arms {
+			armScope := newScope(s)
+			c.declare(arm.Pattern, armScope, false, -1)
+			if arm.Guard != nil {
+				c.expr(arm.Guard, armScope)
+			}
+			c.expr(arm.Body, armScope)
+		}
+
+	case *ast.FunctionLiteral:
+		c.functions = append(c.functions, function{e.Parameters, e.Body, s})
+
+	case *ast.MacroLiteral:
+		c.functions = append(c.functions, function{e.Parameters, e.Body, s})
+
+	case *ast.CallExpression:
+		c.call(e, s)
+
+	case *ast.IndexExpression:
+		c.expr(e.Left, s)
+		c.expr(e.Index, s)
+
+	case *ast.ArrayLiteral:
+		for _, element := range e.Elements {
+			c.expr(element, s)
+		}
+
+	case *ast.HashMapLiteral:
+		for key, value := range e.Pairs {
+			c.expr(key, s)
+			c.expr(value, s)
+		}
+	}
+}
+
+// resolve looks up the binding of ident and reports it if there is none.
+// It returns nil for builtins.
+func (c *checker) resolve(ident *ast.Identifier, s *scope) *binding {
+	if b := s.lookup(ident.Value); b != nil {
+		return b
+	}
+	if !evaluator.IsBuiltin(ident.Value) {
+		c.report(RuleUndefined, ident.Token, "identifier not found: %s", ident.Value)
+	}
+	return nil
+}
+
+func (c *checker) call(call *ast.CallExpression, s *scope) {
+	if ident, ok := call.Function.(*ast.Identifier); ok && ident.Value == "quote" && s.lookup("quote") == nil {
+		// quoted code is not evaluated, except for what is unquoted
+		for _, arg := range call.Arguments {
+			c.unquoted(arg, s)
+		}
+		return
+	}
+
+	c.expr(call.Function, s)
+	for _, arg := range call.Arguments {
+		c.expr(arg, s)
+	}
+
+	want, name := arity(call.Function), "function literal"
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		if b := s.lookup(ident.Value); b != nil {
+			want, name = b.arity, ident.Value
+		}
+	}
+	if want >= 0 && want != len(call.Arguments) {
+		c.report(RuleArity, ast.Start(call),
+			"wrong number of arguments to %s. got=%d, want=%d", name, len(call.Arguments), want)
+	}
+}
+
+// unquoted checks the arguments of the unquote calls in quoted code.
+func (c *checker) unquoted(quoted ast.Node, s *scope) {
+	ast.Modify(quoted, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+		if ident, ok := call.Function.(*ast.Identifier); ok && ident.Value == "unquote" {
+			for _, arg := range call.Arguments {
+				c.expr(arg, s)
+			}
+		}
+		return node
+	})
+}
@@ -0,0 +1,75 @@
+// Package lint finds likely mistakes in Monkey programs without running
+// them. It is used by `monkey lint`.
+package lint
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/lexer"
+	"monkey-go/parser"
+	"sort"
+)
+
+// Rule IDs. Every diagnostic names the rule that reported it, and rules can
+// be disabled by ID.
+const (
+	RuleUndefined     = "undefined"
+	RuleShadowBuiltin = "shadow-builtin"
+	RuleUnused        = "unused"
+	RuleUnreachable   = "unreachable"
+	RuleArity         = "arity"
+)
+
+// Rules describes every rule by ID.
+var Rules = map[string]string{
+	RuleUndefined:     "use of an identifier that is not declared",
+	RuleShadowBuiltin: "a binding hides a builtin function such as len",
+	RuleUnused:        "a let or const binding that is never read",
+	RuleUnreachable:   "statements after a return in the same block",
+	RuleArity:         "a function literal called with the wrong number of arguments",
+}
+
+// Diagnostic is a problem found by a rule.
+type Diagnostic struct {
+	Rule    string
+	Line    int // 1-based
+	Column  int // 1-based, counted in runes
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+}
+
+// Program checks program and returns the diagnostics of all rules except
+// the disabled ones, ordered by position.
+func Program(program *ast.Program, disabled ...string) []Diagnostic {
+	c := &checker{disabled: map[string]bool{}}
+	for _, rule := range disabled {
+		c.disabled[rule] = true
+	}
+
+	c.program(program)
+
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a, b := c.diagnostics[i], c.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.diagnostics
+}
+
+// Source parses src and checks it like Program. Source that does not parse
+// is not checked; a *parser.SyntaxError is returned instead.
+func Source(src []byte, disabled ...string) ([]Diagnostic, error) {
+	p := parser.New(lexer.New(string(src)))
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &parser.SyntaxError{Errors: p.ErrorList()}
+	}
+
+	return Program(program, disabled...), nil
+}
@@ -0,0 +1,94 @@
+package lint
+
+import (
+	"monkey-go/ast"
+	"monkey-go/lexer"
+	"monkey-go/parser"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// undefined
+		{"let x = 1; x + y", []string{"1:16: identifier not found: y (undefined)"}},
+		{"z = 1;", []string{"1:1: identifier not found: z (undefined)"}},
+		{"y; let y = 1; y", []string{"1:1: identifier not found: y (undefined)"}},
+		{"if (true) { let a = 1; a }; a", []string{"1:29: identifier not found: a (undefined)"}},
+		{"len([1]) + first([1]); quote(x)", nil},
+		{"let x = 1; quote(unquote(x) + unquote(nope))", []string{"1:39: identifier not found: nope (undefined)"}},
+		{`match ([1, 2]) { [a, b] if a == b => a + c, {name} => name, _ => 0 }`,
+			[]string{"1:42: identifier not found: c (undefined)"}},
+		// function bodies may refer to names declared after them
+		{`let isEven = fn(n) { n == 0 ? true : isOdd(n - 1) };
+let isOdd = fn(n) { n == 0 ? false : isEven(n - 1) };
+isEven(4);`, nil},
+
+		// shadow-builtin
+		{"let len = fn(x) { x }; len(1)", []string{"1:5: len shadows the builtin function len (shadow-builtin)"}},
+		{"let f = fn(first, [rest]) { first + rest }; f(1, [2])", []string{
+			"1:12: first shadows the builtin function first (shadow-builtin)",
+			"1:20: rest shadows the builtin function rest (shadow-builtin)",
+		}},
+
+		// unused
+		{"let x = 1; let _y = 2;", []string{"1:5: x declared and not used (unused)"}},
+		{"let x = 1; x = 2;", []string{"1:5: x declared and not used (unused)"}},
+		{"let [a, ...b] = [1]; a", []string{"1:12: b declared and not used (unused)"}},
+		{"let f = fn(unusedParam) { let tmp = 1; 2 }; f(0)", []string{"1:31: tmp declared and not used (unused)"}},
+
+		// unreachable
+		{"let f = fn() { return 1; 2; 3 }; f()", []string{"1:26: unreachable code (unreachable)"}},
+		{"let f = fn(x) { if (x) { return 1; } 2 }; f(true)", nil},
+
+		// arity
+		{"let add = fn(a, b) { a + b }; add(1)", []string{"1:31: wrong number of arguments to add. got=1, want=2 (arity)"}},
+		{"fn(x) { x }(1, 2)", []string{"1:1: wrong number of arguments to function literal. got=2, want=1 (arity)"}},
+		{"let f = fn() { 1 }; f = fn(x) { x }; f(1)", nil},
+		{"let m = macro(a) { quote(unquote(a)) }; m(1, 2)", []string{"1:41: wrong number of arguments to m. got=2, want=1 (arity)"}},
+		{"let f = fn(a) { a }; let g = fn() { f(1, 2) }; g()", []string{"1:37: wrong number of arguments to f. got=2, want=1 (arity)"}},
+	}
+
+	for _, tt := range tests {
+		diagnostics := Program(parse(t, tt.input))
+
+		var got []string
+		for _, d := range diagnostics {
+			got = append(got, d.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q - wrong diagnostics.\nexpected:\n%s\ngot:\n%s",
+				tt.input, strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestDisabledRules(t *testing.T) {
+	input := "let len = 1; let x = y;"
+
+	diagnostics := Program(parse(t, input), RuleUnused, RuleShadowBuiltin)
+	if len(diagnostics) != 1 || diagnostics[0].Rule != RuleUndefined {
+		t.Fatalf("expected only the undefined diagnostic. got=%v", diagnostics)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source([]byte("let = 1;"))
+	if err == nil {
+		t.Fatalf("expected an error for invalid source")
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey-go/lint"
+	"os"
+	"sort"
+	"strings"
+)
+
+// runLint implements `monkey lint [-disable rules] [files...]`. Without
+// files, standard input is checked. The exit status is 1 if anything was
+// reported.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	disable := flags.String("disable", "", "comma-separated rule IDs to skip")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey lint [-disable rules] [files...]\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(flags.Output(), "rules:\n")
+		for _, id := range ruleIDs() {
+			fmt.Fprintf(flags.Output(), "  %-16s %s\n", id, lint.Rules[id])
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var disabled []string
+	if *disable != "" {
+		for _, id := range strings.Split(*disable, ",") {
+			id = strings.TrimSpace(id)
+			if _, ok := lint.Rules[id]; !ok {
+				fmt.Fprintf(os.Stderr, "monkey lint: unknown rule %q\n", id)
+				return 2
+			}
+			disabled = append(disabled, id)
+		}
+	}
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return lintSource("<stdin>", src, disabled)
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		if lintSource(path, src, disabled) != 0 {
+			status = 1
+		}
+	}
+	return status
+}
+
+func lintSource(name string, src []byte, disabled []string) int {
+	diagnostics, err := lint.Source(src, disabled...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, sourceError(name, src, err))
+		return 1
+	}
+
+	for _, d := range diagnostics {
+		fmt.Printf("%s:%s\n", name, d)
+	}
+	if len(diagnostics) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func ruleIDs() []string {
+	ids := make([]string, 0, len(lint.Rules))
+	for id := range lint.Rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
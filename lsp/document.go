@@ -0,0 +1,149 @@
+package lsp
+
+import (
+	"monkey-go/ast"
+	"monkey-go/lexer"
+	"monkey-go/lint"
+	"monkey-go/parser"
+	"monkey-go/token"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// document is an open text document and the result of analysing it.
+type document struct {
+	uri     string
+	version int
+	text    string
+	lines   [][]rune
+
+	program *ast.Program
+	errors  []*parser.Error
+	index   *index
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version, text: text}
+	for _, line := range strings.Split(text, "\n") {
+		d.lines = append(d.lines, []rune(strings.TrimSuffix(line, "\r")))
+	}
+
+	p := parser.New(lexer.New(text))
+	d.program = p.ParseProgram()
+	d.errors = p.ErrorList()
+	d.index = newIndex(d.program)
+	return d
+}
+
+// diagnostics returns the parser errors of d, or the lint findings if it
+// parses.
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, err := range d.errors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.wordRange(err.Line, err.Column),
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  err.Message,
+		})
+	}
+	if len(d.errors) > 0 {
+		return diagnostics
+	}
+
+	for _, finding := range lint.Program(d.program) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.wordRange(finding.Line, finding.Column),
+			Severity: SeverityWarning,
+			Code:     finding.Rule,
+			Source:   "monkey lint",
+			Message:  finding.Message,
+		})
+	}
+	return diagnostics
+}
+
+// position converts a 1-based line and rune column to an LSP position.
+func (d *document) position(line, column int) Position {
+	if line < 1 {
+		return Position{}
+	}
+	if line > len(d.lines) {
+		return d.end()
+	}
+	runes := d.lines[line-1]
+	if column < 1 {
+		column = 1
+	}
+	if column-1 > len(runes) {
+		column = len(runes) + 1
+	}
+	return Position{Line: line - 1, Character: utf16Len(runes[:column-1])}
+}
+
+// location converts an LSP position to a 1-based line and rune column.
+func (d *document) location(pos Position) (line, column int) {
+	if pos.Line >= len(d.lines) {
+		return pos.Line + 1, 1
+	}
+	units := 0
+	for i, r := range d.lines[pos.Line] {
+		if units >= pos.Character {
+			return pos.Line + 1, i + 1
+		}
+		units += utf16Len([]rune{r})
+	}
+	return pos.Line + 1, len(d.lines[pos.Line]) + 1
+}
+
+// end returns the position after the last character of d.
+func (d *document) end() Position {
+	last := len(d.lines) - 1
+	return Position{Line: last, Character: utf16Len(d.lines[last])}
+}
+
+// identRange returns the range of an identifier in d.
+func (d *document) identRange(ident *ast.Identifier) Range {
+	tok := ident.Token
+	return Range{
+		Start: d.position(tok.Line, tok.Column),
+		End:   d.position(tok.Line, tok.Column+len([]rune(ident.Value))),
+	}
+}
+
+// wordRange returns the range of the identifier or number starting at line
+// and column, or of the single character there.
+func (d *document) wordRange(line, column int) Range {
+	start := d.position(line, column)
+	end := column
+	if line >= 1 && line <= len(d.lines) {
+		runes := d.lines[line-1]
+		for end-1 < len(runes) && isWordRune(runes[end-1]) {
+			end++
+		}
+		if end == column && end-1 < len(runes) {
+			end++
+		}
+	}
+	return Range{Start: start, End: d.position(line, end)}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenAt returns a position token for an LSP position, to compare with the
+// tokens of the AST.
+func (d *document) tokenAt(pos Position) token.Token {
+	line, column := d.location(pos)
+	return token.Token{Line: line, Column: column}
+}
+
+func utf16Len(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
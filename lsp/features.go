@@ -0,0 +1,220 @@
+package lsp
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/evaluator"
+	"monkey-go/format"
+	"monkey-go/token"
+	"strings"
+)
+
+// hover describes the identifier at pos: the declaration of a binding or
+// the documentation of a builtin.
+func (d *document) hover(pos Position) *Hover {
+	ref, ok := d.index.referenceAt(d.tokenAt(pos))
+	if !ok {
+		return nil
+	}
+
+	var value string
+	if ref.sym != nil {
+		value = d.describe(ref.sym)
+	} else if doc, ok := evaluator.BuiltinDoc(ref.ident.Value); ok {
+		value = fmt.Sprintf("```monkey\nbuiltin %s\n```\n%s", ref.ident.Value, doc)
+	} else {
+		return nil
+	}
+
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: value},
+		Range:    d.identRange(ref.ident),
+	}
+}
+
+// describe returns the declaration of sym as Markdown: the source line of a
+// let or const, or the kind of the name otherwise.
+func (d *document) describe(sym *symbol) string {
+	line := sym.ident.Token.Line
+
+	switch sym.kind {
+	case kindParameter:
+		return fmt.Sprintf("```monkey\n(parameter) %s\n```\nDeclared on line %d.", sym.name, line)
+	case kindPattern:
+		return fmt.Sprintf("```monkey\n(pattern) %s\n```\nBound by a match arm on line %d.", sym.name, line)
+	}
+
+	source := sym.name
+	if line >= 1 && line <= len(d.lines) {
+		source = strings.TrimSpace(string(d.lines[line-1]))
+	}
+	return fmt.Sprintf("```monkey\n%s\n```\nDeclared on line %d.", source, line)
+}
+
+// definition returns where the identifier at pos is declared.
+func (d *document) definition(pos Position) *Location {
+	ref, ok := d.index.referenceAt(d.tokenAt(pos))
+	if !ok || ref.sym == nil {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: d.identRange(ref.sym.ident)}
+}
+
+// completion returns the names that can be used at pos, followed by the
+// builtins and keywords. The client filters them by the prefix typed.
+func (d *document) completion(pos Position) []CompletionItem {
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+
+	for _, sym := range d.index.visible(d.tokenAt(pos)) {
+		seen[sym.name] = true
+		items = append(items, CompletionItem{
+			Label:  sym.name,
+			Kind:   completionKind(sym),
+			Detail: detail(sym),
+		})
+	}
+	for _, name := range evaluator.BuiltinNames() {
+		if seen[name] {
+			continue // shadowed
+		}
+		doc, _ := evaluator.BuiltinDoc(name)
+		items = append(items, CompletionItem{Label: name, Kind: CompletionFunction, Detail: doc})
+	}
+	for _, word := range token.Keywords() {
+		items = append(items, CompletionItem{Label: word, Kind: CompletionKeyword})
+	}
+	return items
+}
+
+func completionKind(sym *symbol) int {
+	switch {
+	case isFunction(sym.value):
+		return CompletionFunction
+	case sym.kind == kindConst:
+		return CompletionConstant
+	}
+	return CompletionVariable
+}
+
+// detail returns a short description of sym, such as fn(a, b) for a
+// function.
+func detail(sym *symbol) string {
+	switch value := sym.value.(type) {
+	case *ast.FunctionLiteral:
+		return "fn" + parameters(value.Parameters)
+	case *ast.MacroLiteral:
+		return "macro" + parameters(value.Parameters)
+	}
+
+	switch sym.kind {
+	case kindConst:
+		return "const"
+	case kindParameter:
+		return "parameter"
+	case kindPattern:
+		return "pattern"
+	}
+	return "let"
+}
+
+func parameters(params []ast.Expression) string {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.String()
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func isFunction(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.FunctionLiteral, *ast.MacroLiteral:
+		return true
+	}
+	return false
+}
+
+// symbols returns the let and const bindings of the program. Bindings in a
+// function body are children of the binding of the function.
+func (d *document) symbols() []DocumentSymbol {
+	return d.statementSymbols(d.program.Statements)
+}
+
+func (d *document) statementSymbols(stmts []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, stmt := range stmts {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let == nil {
+			continue
+		}
+
+		for _, ident := range names(let.Target()) {
+			sym := DocumentSymbol{
+				Name:           ident.Value,
+				Kind:           SymbolVariable,
+				SelectionRange: d.identRange(ident),
+			}
+			if let.IsConst() {
+				sym.Kind = SymbolConstant
+			}
+			sym.Range = Range{
+				Start: d.position(let.Token.Line, let.Token.Column),
+				End:   sym.SelectionRange.End,
+			}
+
+			var body *ast.BlockStatement
+			switch value := let.Value.(type) {
+			case *ast.FunctionLiteral:
+				sym.Detail, body = "fn"+parameters(value.Parameters), value.Body
+			case *ast.MacroLiteral:
+				sym.Detail, body = "macro"+parameters(value.Parameters), value.Body
+			}
+			if body != nil && let.Name != nil {
+				sym.Kind = SymbolFunction
+				sym.Range.End = d.position(body.Rbrace.Line, body.Rbrace.Column+1)
+				sym.Children = d.statementSymbols(body.Statements)
+			}
+			symbols = append(symbols, sym)
+		}
+	}
+	return symbols
+}
+
+// names returns the identifiers a let binds, in source order.
+func names(pattern ast.Expression) []*ast.Identifier {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			return []*ast.Identifier{pattern}
+		}
+	case *ast.ArrayPattern:
+		var idents []*ast.Identifier
+		for _, element := range pattern.Elements {
+			idents = append(idents, names(element)...)
+		}
+		if pattern.Rest != nil {
+			idents = append(idents, names(pattern.Rest)...)
+		}
+		return idents
+	case *ast.HashPattern:
+		var idents []*ast.Identifier
+		for _, pair := range pattern.Pairs {
+			idents = append(idents, names(pair.Value)...)
+		}
+		return idents
+	}
+	return nil
+}
+
+// formatting returns an edit that replaces the document with its formatted
+// version, or no edits if it is formatted already or does not parse.
+func (d *document) formatting() []TextEdit {
+	formatted, err := format.Source([]byte(d.text))
+	if err != nil || string(formatted) == d.text {
+		return []TextEdit{}
+	}
+	return []TextEdit{{
+		Range:   Range{Start: Position{}, End: d.end()},
+		NewText: string(formatted),
+	}}
+}
@@ -0,0 +1,313 @@
+package lsp
+
+import (
+	"math"
+	"monkey-go/ast"
+	"monkey-go/token"
+	"sort"
+)
+
+type symbolKind int
+
+const (
+	kindLet symbolKind = iota
+	kindConst
+	kindParameter
+	kindPattern // bound by a match arm
+)
+
+// symbol is a name declared by let, const, a parameter or a match pattern.
+type symbol struct {
+	name  string
+	kind  symbolKind
+	ident *ast.Identifier // where the name is declared
+	value ast.Expression  // the value of a let or const without destructuring
+}
+
+// reference is an identifier in the source and the symbol it names. sym is
+// nil for builtins and undeclared names.
+type reference struct {
+	ident *ast.Identifier
+	sym   *symbol
+}
+
+// region is the part of the source in which the names of a scope can be
+// used: the program, a function, an if block or a match arm.
+type region struct {
+	start, end token.Token // end is exclusive
+	function   bool
+	symbols    []*symbol
+}
+
+func (r *region) contains(pos token.Token) bool {
+	return !before(pos, r.start) && before(pos, r.end)
+}
+
+type scope struct {
+	names  map[string]*symbol
+	outer  *scope
+	region *region
+}
+
+func (s *scope) lookup(name string) *symbol {
+	for ; s != nil; s = s.outer {
+		if sym, ok := s.names[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// function is a function or macro body whose names are resolved after the
+// statements around it, as the evaluator runs it when it is called.
+type function struct {
+	literal token.Token
+	params  []ast.Expression
+	body    *ast.BlockStatement
+	scope   *scope
+}
+
+// index resolves the identifiers of a program with the scoping rules of the
+// evaluator. It mirrors the checker of the lint package.
+type index struct {
+	refs      []reference
+	regions   []*region
+	functions []function
+}
+
+var endOfFile = token.Token{Type: token.EOF, Line: math.MaxInt}
+
+func newIndex(program *ast.Program) *index {
+	ix := &index{}
+	ix.statements(program.Statements, ix.newScope(nil, token.Token{}, endOfFile, false))
+
+	for len(ix.functions) > 0 {
+		fn := ix.functions[0]
+		ix.functions = ix.functions[1:]
+
+		s := ix.newScope(fn.scope, fn.literal, fn.body.Rbrace, true)
+		for _, param := range fn.params {
+			ix.declare(param, s, kindParameter, nil)
+		}
+		ix.statements(fn.body.Statements, s)
+	}
+	return ix
+}
+
+func (ix *index) newScope(outer *scope, start, end token.Token, function bool) *scope {
+	r := &region{start: start, end: end, function: function}
+	ix.regions = append(ix.regions, r)
+	return &scope{names: map[string]*symbol{}, outer: outer, region: r}
+}
+
+func (ix *index) statements(stmts []ast.Statement, s *scope) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			if stmt == nil {
+				continue // a let statement that did not parse
+			}
+			ix.expr(stmt.Value, s)
+			kind := kindLet
+			if stmt.IsConst() {
+				kind = kindConst
+			}
+			ix.declare(stmt.Target(), s, kind, stmt.Value)
+
+		case *ast.ReturnStatement:
+			ix.expr(stmt.ReturnValue, s)
+
+		case *ast.ExpressionStatement:
+			ix.expr(stmt.Expression, s)
+		}
+	}
+}
+
+// declare binds the names in pattern in s. value is only kept for a plain
+// identifier.
+func (ix *index) declare(pattern ast.Expression, s *scope, kind symbolKind, value ast.Expression) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return
+		}
+		sym := &symbol{name: pattern.Value, kind: kind, ident: pattern, value: value}
+		s.names[sym.name] = sym
+		s.region.symbols = append(s.region.symbols, sym)
+		ix.refs = append(ix.refs, reference{pattern, sym})
+
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			ix.declare(element, s, kind, nil)
+		}
+		if pattern.Rest != nil {
+			ix.declare(pattern.Rest, s, kind, nil)
+		}
+
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			ix.declare(pair.Value, s, kind, nil)
+		}
+	}
+}
+
+func (ix *index) expr(e ast.Expression, s *scope) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		ix.refs = append(ix.refs, reference{e, s.lookup(e.Value)})
+
+	case *ast.PrefixExpression:
+		ix.expr(e.Right, s)
+
+	case *ast.InfixExpression:
+		ix.expr(e.Left, s)
+		ix.expr(e.Right, s)
+
+	case *ast.ConditionalExpression:
+		ix.expr(e.Condition, s)
+		ix.expr(e.Consequence, s)
+		ix.expr(e.Alternative, s)
+
+	case *ast.IfExpression:
+		ix.expr(e.Condition, s)
+		ix.block(e.Consequence, s)
+		if e.ElseIf != nil {
+			ix.expr(e.ElseIf, s)
+		}
+		if e.Alternative != nil {
+			ix.block(e.Alternative, s)
+		}
+
+	case *ast.MatchExpression:
+		ix.expr(e.Subject, s)
+		for i, arm := range e.Arms {
+			end := e.Rbrace
+			if i+1 < len(e.Arms) {
+				end = ast.Start(e.Arms[i+1].Pattern)
+			}
+			armScope := ix.newScope(s, ast.Start(arm.Pattern), end, false)
+			ix.declare(arm.Pattern, armScope, kindPattern, nil)
+			if arm.Guard != nil {
+				ix.expr(arm.Guard, armScope)
+			}
+			ix.expr(arm.Body, armScope)
+		}
+
+	case *ast.FunctionLiteral:
+		ix.functions = append(ix.functions, function{e.Token, e.Parameters, e.Body, s})
+
+	case *ast.MacroLiteral:
+		ix.functions = append(ix.functions, function{e.Token, e.Parameters, e.Body, s})
+
+	case *ast.CallExpression:
+		if ident, ok := e.Function.(*ast.Identifier); ok && ident.Value == "quote" && s.lookup("quote") == nil {
+			ix.expr(ident, s)
+			for _, arg := range e.Arguments {
+				ix.unquoted(arg, s)
+			}
+			return
+		}
+		ix.expr(e.Function, s)
+		for _, arg := range e.Arguments {
+			ix.expr(arg, s)
+		}
+
+	case *ast.IndexExpression:
+		ix.expr(e.Left, s)
+		ix.expr(e.Index, s)
+
+	case *ast.ArrayLiteral:
+		for _, element := range e.Elements {
+			ix.expr(element, s)
+		}
+
+	case *ast.HashMapLiteral:
+		for key, value := range e.Pairs {
+			ix.expr(key, s)
+			ix.expr(value, s)
+		}
+	}
+}
+
+func (ix *index) block(b *ast.BlockStatement, s *scope) {
+	if b == nil {
+		return
+	}
+	ix.statements(b.Statements, ix.newScope(s, b.Token, b.Rbrace, false))
+}
+
+// unquoted resolves the arguments of the unquote calls in quoted code, the
+// only part of it that is evaluated where it is written.
+func (ix *index) unquoted(quoted ast.Node, s *scope) {
+	ast.Modify(quoted, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+		if ident, ok := call.Function.(*ast.Identifier); ok && ident.Value == "unquote" {
+			for _, arg := range call.Arguments {
+				ix.expr(arg, s)
+			}
+		}
+		return node
+	})
+}
+
+// referenceAt returns the identifier at pos. The position just after an
+// identifier also counts, as that is where the cursor is while typing it.
+func (ix *index) referenceAt(pos token.Token) (reference, bool) {
+	var after reference
+	found := false
+	for _, ref := range ix.refs {
+		tok := ref.ident.Token
+		if tok.Line != pos.Line || pos.Column < tok.Column {
+			continue
+		}
+		end := tok.Column + len([]rune(ref.ident.Value))
+		if pos.Column < end {
+			return ref, true
+		}
+		if pos.Column == end {
+			after, found = ref, true
+		}
+	}
+	return after, found
+}
+
+// visible returns the symbols that can be used at pos, innermost first. A
+// name declared further down its scope is visible inside a function body,
+// as the body runs after the declaration.
+func (ix *index) visible(pos token.Token) []*symbol {
+	var regions []*region
+	for _, r := range ix.regions {
+		if r.contains(pos) {
+			regions = append(regions, r)
+		}
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		return before(regions[j].start, regions[i].start)
+	})
+
+	var symbols []*symbol
+	seen := map[string]bool{}
+	inFunction := false
+	for _, r := range regions {
+		for _, sym := range r.symbols {
+			if seen[sym.name] {
+				continue
+			}
+			if inFunction || sym.kind == kindParameter || sym.kind == kindPattern || before(sym.ident.Token, pos) {
+				seen[sym.name] = true
+				symbols = append(symbols, sym)
+			}
+		}
+		if r.function {
+			inFunction = true
+		}
+	}
+	return symbols
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
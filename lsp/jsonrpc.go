@@ -0,0 +1,23 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"monkey-go/internal/framing"
+)
+
+// readMessage reads the body of one message.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	return framing.Read(r)
+}
+
+// writeMessage writes msg as JSON-RPC 2.0.
+func writeMessage(w io.Writer, msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return framing.Write(w, body)
+}
@@ -0,0 +1,227 @@
+// Package lsp implements a Language Server Protocol server for Monkey, used
+// by `monkey lsp`. It speaks JSON-RPC over a pair of streams, usually the
+// standard input and output of the server process.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNoShutdown is returned by Serve when the client sends exit without a
+// shutdown request first. The specification asks the server to exit with
+// status 1 then.
+var ErrNoShutdown = errors.New("exit without shutdown")
+
+type server struct {
+	out         io.Writer
+	err         error // the first error writing to out
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// Serve reads messages from in and writes responses and notifications to
+// out until the client sends exit or in ends. Documents are synchronized in
+// full on every change.
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{out: out, docs: map[string]*document{}}
+	r := bufio.NewReader(in)
+
+	for s.err == nil {
+		body, err := readMessage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.reply(nil, nil, &responseError{codeParseError, err.Error()})
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+
+		if msg.ID == nil {
+			s.notification(&msg)
+			continue
+		}
+		result, rerr := s.request(&msg)
+		s.reply(msg.ID, result, rerr)
+	}
+	return s.err
+}
+
+func (s *server) send(msg *message) {
+	if s.err == nil {
+		s.err = writeMessage(s.out, msg)
+	}
+}
+
+func (s *server) reply(id *json.RawMessage, result any, rerr *responseError) {
+	if id == nil {
+		null := json.RawMessage("null")
+		id = &null
+	}
+	if rerr != nil {
+		s.send(&message{ID: id, Error: rerr})
+		return
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		s.send(&message{ID: id, Error: &responseError{codeInvalidParams, err.Error()}})
+		return
+	}
+	s.send(&message{ID: id, Result: body})
+}
+
+func (s *server) notify(method string, params any) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.send(&message{Method: method, Params: body})
+}
+
+func (s *server) request(msg *message) (any, *responseError) {
+	if msg.Method == "initialize" {
+		s.initialized = true
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:           syncFull,
+				HoverProvider:              true,
+				DefinitionProvider:         true,
+				CompletionProvider:         map[string]any{},
+				DocumentSymbolProvider:     true,
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "monkey-lsp"},
+		}, nil
+	}
+	if !s.initialized {
+		return nil, &responseError{codeServerNotInitialized, "server not initialized"}
+	}
+
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.hover(params.Position), nil
+		}
+		return nil, nil
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.definition(params.Position), nil
+		}
+		return nil, nil
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.completion(params.Position), nil
+		}
+		return nil, nil
+
+	case "textDocument/documentSymbol":
+		var params DocumentParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.symbols(), nil
+		}
+		return nil, nil
+
+	case "textDocument/formatting":
+		var params DocumentParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if d := s.docs[params.TextDocument.URI]; d != nil {
+			return d.formatting(), nil
+		}
+		return nil, nil
+	}
+
+	return nil, &responseError{codeMethodNotFound, fmt.Sprintf("method not found: %s", msg.Method)}
+}
+
+// notification handles a message that gets no response. Unknown
+// notifications, such as initialized, are ignored.
+func (s *server) notification(msg *message) {
+	if !s.initialized {
+		return
+	}
+
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if decode(msg.Params, &params) == nil {
+			doc := params.TextDocument
+			s.open(newDocument(doc.URI, doc.Version, doc.Text))
+		}
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if decode(msg.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			doc := params.TextDocument
+			text := params.ContentChanges[len(params.ContentChanges)-1].Text
+			s.open(newDocument(doc.URI, doc.Version, text))
+		}
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if decode(msg.Params, &params) == nil {
+			delete(s.docs, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []Diagnostic{},
+			})
+		}
+	}
+}
+
+// open stores d, replacing an earlier version, and publishes its
+// diagnostics.
+func (s *server) open(d *document) {
+	s.docs[d.uri] = d
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: d.diagnostics(),
+	})
+}
+
+func decode(params json.RawMessage, v any) *responseError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{codeInvalidParams, err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,471 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const uri = "file:///test.monkey"
+
+// script frames messages for Serve. Each message is a method and its params;
+// requests are numbered from 1 in order, and methods starting with '!' are
+// sent as notifications.
+func script(t *testing.T, messages ...any) io.Reader {
+	t.Helper()
+
+	var in bytes.Buffer
+	id := 0
+	for i := 0; i+1 < len(messages); i += 2 {
+		method := messages[i].(string)
+		msg := map[string]any{"jsonrpc": "2.0", "params": messages[i+1]}
+		if strings.HasPrefix(method, "!") {
+			msg["method"] = method[1:]
+		} else {
+			id++
+			msg["id"] = id
+			msg["method"] = method
+		}
+
+		body, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("json.Marshal: %v", err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	return &in
+}
+
+// run serves a session that opens src and then sends messages, and returns
+// the messages written by the server after the diagnostics for src.
+func run(t *testing.T, src string, messages ...any) []message {
+	t.Helper()
+
+	prologue := []any{
+		"initialize", map[string]any{},
+		"!initialized", map[string]any{},
+		"!textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "monkey", "version": 1, "text": src},
+		},
+	}
+	epilogue := []any{"shutdown", nil, "!exit", nil}
+
+	out := serve(t, script(t, append(append(prologue, messages...), epilogue...)...))
+	if len(out) < 3 {
+		t.Fatalf("expected at least 3 messages, got %d", len(out))
+	}
+	// drop the responses to initialize and shutdown and the first diagnostics
+	return out[2 : len(out)-1]
+}
+
+func serve(t *testing.T, in io.Reader) []message {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := Serve(in, &out); err != nil {
+		t.Fatalf("Serve returned %v", err)
+	}
+
+	var messages []message
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err == io.EOF {
+			return messages
+		}
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("json.Unmarshal(%s): %v", body, err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func position(line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func result(t *testing.T, msg message, v any) {
+	t.Helper()
+	if msg.Error != nil {
+		t.Fatalf("unexpected error %d: %s", msg.Error.Code, msg.Error.Message)
+	}
+	if err := json.Unmarshal(msg.Result, v); err != nil {
+		t.Fatalf("json.Unmarshal(%s): %v", msg.Result, err)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	out := serve(t, script(t,
+		"shutdown", nil,
+		"initialize", map[string]any{},
+		"textDocument/rename", map[string]any{},
+		"shutdown", nil,
+		"!exit", nil,
+	))
+	if len(out) != 4 {
+		t.Fatalf("wrong number of messages. want=4, got=%d", len(out))
+	}
+
+	if out[0].Error == nil || out[0].Error.Code != codeServerNotInitialized {
+		t.Errorf("request before initialize not rejected. got=%+v", out[0].Error)
+	}
+
+	var init InitializeResult
+	result(t, out[1], &init)
+	caps := init.Capabilities
+	if caps.TextDocumentSync != syncFull || !caps.HoverProvider || !caps.DefinitionProvider ||
+		caps.CompletionProvider == nil || !caps.DocumentSymbolProvider || !caps.DocumentFormattingProvider {
+		t.Errorf("wrong capabilities. got=%+v", caps)
+	}
+
+	if out[2].Error == nil || out[2].Error.Code != codeMethodNotFound {
+		t.Errorf("unknown method not rejected. got=%+v", out[2].Error)
+	}
+
+	if string(*out[3].ID) != "4" || string(out[3].Result) != "null" {
+		t.Errorf("wrong shutdown response. got id=%s result=%s", *out[3].ID, out[3].Result)
+	}
+
+	var discard bytes.Buffer
+	err := Serve(script(t, "initialize", map[string]any{}, "!exit", nil), &discard)
+	if err != ErrNoShutdown {
+		t.Errorf("exit without shutdown. want=%v, got=%v", ErrNoShutdown, err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	out := run(t, "let x = ;\nlet y = \"é\" + @;",
+		"!textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []any{map[string]any{"text": "let x = 1;\nlen(nope)"}},
+		},
+		"!textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": uri}},
+	)
+	// the diagnostics of didOpen were dropped by run; serve them again
+	opened := serve(t, script(t,
+		"initialize", map[string]any{},
+		"!textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "version": 1, "text": "let x = ;\nlet y = \"é\" + @;"},
+		},
+	))
+	out = append(opened[1:], out...)
+
+	tests := []struct {
+		version  int
+		expected []string
+	}{
+		{1, []string{
+			"0:8-0:9 error: no prefix parse function for ; found",
+			"1:14-1:15 error: unexpected character '@'",
+		}},
+		{2, []string{
+			"0:4-0:5 warning: x declared and not used (unused)",
+			"1:4-1:8 warning: identifier not found: nope (undefined)",
+		}},
+		{0, nil},
+	}
+	if len(out) != len(tests) {
+		t.Fatalf("wrong number of messages. want=%d, got=%d", len(tests), len(out))
+	}
+
+	for i, tt := range tests {
+		if out[i].Method != "textDocument/publishDiagnostics" {
+			t.Fatalf("message %d is not publishDiagnostics. got=%q", i, out[i].Method)
+		}
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(out[i].Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		if params.URI != uri || params.Version != tt.version {
+			t.Errorf("wrong document. want=%s@%d, got=%s@%d", uri, tt.version, params.URI, params.Version)
+		}
+
+		var got []string
+		for _, d := range params.Diagnostics {
+			severity := map[int]string{SeverityError: "error", SeverityWarning: "warning"}[d.Severity]
+			s := fmt.Sprintf("%d:%d-%d:%d %s: %s", d.Range.Start.Line, d.Range.Start.Character,
+				d.Range.End.Line, d.Range.End.Character, severity, d.Message)
+			if d.Code != "" {
+				s += " (" + d.Code + ")"
+			}
+			got = append(got, s)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong diagnostics for version %d.\nwant=%q\ngot=%q", tt.version, tt.expected, got)
+		}
+	}
+}
+
+const program = `let add = fn(a, b) { a + b };
+const limit = 10;
+let [first2, ...others] = [1, 2, 3];
+let twice = fn(f, x) {
+    let once = f(x);
+    f(once)
+};
+twice(fn(n) { add(n, limit) + len("🐒" + later) }, 1);
+let later = "x";
+match (others) { [head] => head, _ => 0 }`
+
+func TestHover(t *testing.T) {
+	tests := []struct {
+		line, character int
+		expected        string // "" for no hover
+	}{
+		{7, 0, "```monkey\nlet twice = fn(f, x) {\n```\nDeclared on line 4."},
+		{7, 15, "```monkey\nlet add = fn(a, b) { a + b };\n```\nDeclared on line 1."},
+		{7, 22, "```monkey\nconst limit = 10;\n```\nDeclared on line 2."},
+		{0, 21, "```monkey\n(parameter) a\n```\nDeclared on line 1."},
+		{4, 15, "```monkey\n(parameter) f\n```\nDeclared on line 4."},
+		{7, 31, "```monkey\nbuiltin len\n```\nlen(x) returns the number of characters in a string or elements in an array."},
+		{7, 43, "```monkey\nlet later = \"x\";\n```\nDeclared on line 9."},
+		{9, 29, "```monkey\n(pattern) head\n```\nBound by a match arm on line 10."},
+		{0, 10, ""},
+		{1, 16, ""},
+	}
+
+	var messages []any
+	for _, tt := range tests {
+		messages = append(messages, "textDocument/hover", position(tt.line, tt.character))
+	}
+	out := run(t, program, messages...)
+
+	for i, tt := range tests {
+		var hover *Hover
+		result(t, out[i], &hover)
+		got := ""
+		if hover != nil {
+			got = hover.Contents.Value
+		}
+		if got != tt.expected {
+			t.Errorf("wrong hover at %d:%d.\nwant=%q\ngot=%q", tt.line, tt.character, tt.expected, got)
+		}
+	}
+
+	// after a non-BMP character, columns are counted in UTF-16
+	var hover *Hover
+	result(t, out[6], &hover)
+	expected := Range{Start: Position{7, 41}, End: Position{7, 46}}
+	if hover == nil || hover.Range != expected {
+		t.Errorf("wrong hover range for later. want=%+v, got=%+v", expected, hover)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	tests := []struct {
+		line, character int
+		expected        string // "" for no definition
+	}{
+		{7, 2, "3:4-3:9"},    // twice
+		{7, 15, "0:4-0:7"},   // add, from inside a function literal argument
+		{7, 18, "7:9-7:10"},  // n
+		{5, 6, "4:8-4:12"},   // once
+		{0, 21, "0:13-0:14"}, // a
+		{7, 43, "8:4-8:9"},   // later, declared after its use in a function
+		{9, 28, "9:18-9:22"}, // head
+		{2, 6, "2:5-2:11"},   // first2 at its declaration
+		{7, 31, ""},          // len
+		{1, 14, ""},          // 10
+	}
+
+	var messages []any
+	for _, tt := range tests {
+		messages = append(messages, "textDocument/definition", position(tt.line, tt.character))
+	}
+	out := run(t, program, messages...)
+
+	for i, tt := range tests {
+		var location *Location
+		result(t, out[i], &location)
+		got := ""
+		if location != nil {
+			r := location.Range
+			got = fmt.Sprintf("%d:%d-%d:%d", r.Start.Line, r.Start.Character, r.End.Line, r.End.Character)
+			if location.URI != uri {
+				t.Errorf("wrong uri. got=%q", location.URI)
+			}
+		}
+		if got != tt.expected {
+			t.Errorf("wrong definition at %d:%d. want=%q, got=%q", tt.line, tt.character, tt.expected, got)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	tests := []struct {
+		line, character int
+		present         []string
+		absent          []string
+	}{
+		// top level, before later is declared
+		{7, 0, []string{"add", "limit", "first2", "others", "twice", "len", "first", "quote", "fn", "let"},
+			[]string{"a", "once", "later", "head"}},
+		// inside twice: parameters, locals and every top-level name
+		{5, 4, []string{"f", "x", "once", "add", "later"}, []string{"a", "head"}},
+		// inside a match arm
+		{9, 28, []string{"head", "others"}, []string{"f"}},
+	}
+
+	var messages []any
+	for _, tt := range tests {
+		messages = append(messages, "textDocument/completion", position(tt.line, tt.character))
+	}
+	out := run(t, program, messages...)
+
+	for i, tt := range tests {
+		var items []CompletionItem
+		result(t, out[i], &items)
+		labels := map[string]CompletionItem{}
+		for _, item := range items {
+			labels[item.Label] = item
+		}
+		for _, name := range tt.present {
+			if _, ok := labels[name]; !ok {
+				t.Errorf("completion at %d:%d is missing %q", tt.line, tt.character, name)
+			}
+		}
+		for _, name := range tt.absent {
+			if _, ok := labels[name]; ok {
+				t.Errorf("completion at %d:%d should not have %q", tt.line, tt.character, name)
+			}
+		}
+	}
+
+	var items []CompletionItem
+	result(t, out[0], &items)
+	for _, item := range items {
+		if item.Label == "add" && (item.Kind != CompletionFunction || item.Detail != "fn(a, b)") {
+			t.Errorf("wrong item for add. got=%+v", item)
+		}
+		if item.Label == "limit" && item.Kind != CompletionConstant {
+			t.Errorf("wrong item for limit. got=%+v", item)
+		}
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	out := run(t, program, "textDocument/documentSymbol", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+	})
+
+	var symbols []DocumentSymbol
+	result(t, out[0], &symbols)
+
+	var got []string
+	var walk func(prefix string, symbols []DocumentSymbol)
+	walk = func(prefix string, symbols []DocumentSymbol) {
+		for _, s := range symbols {
+			r := s.Range
+			got = append(got, fmt.Sprintf("%s%s %d %s %d:%d-%d:%d", prefix, s.Name, s.Kind, s.Detail,
+				r.Start.Line, r.Start.Character, r.End.Line, r.End.Character))
+			walk(prefix+"  ", s.Children)
+		}
+	}
+	walk("", symbols)
+
+	expected := []string{
+		"add 12 fn(a, b) 0:0-0:28",
+		"limit 14  1:0-1:11",
+		"first2 13  2:0-2:11",
+		"others 13  2:0-2:22",
+		"twice 12 fn(f, x) 3:0-6:1",
+		"  once 13  4:4-4:12",
+		"later 13  8:0-8:9",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong symbols.\nwant=%q\ngot=%q", expected, got)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []TextEdit
+	}{
+		{"let x=1\nlet  y=2", []TextEdit{{
+			Range:   Range{Start: Position{0, 0}, End: Position{1, 8}},
+			NewText: "let x = 1;\nlet y = 2;\n",
+		}}},
+		{"let x = 1;\n", []TextEdit{}},
+		{"let x = ;", []TextEdit{}},
+	}
+
+	for _, tt := range tests {
+		out := run(t, tt.input, "textDocument/formatting", map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"options":      map[string]any{"tabSize": 4, "insertSpaces": true},
+		})
+		// run drops the diagnostics of didOpen, so out[0] is the response
+		var edits []TextEdit
+		result(t, out[0], &edits)
+
+		if len(edits) != len(tt.expected) {
+			t.Errorf("wrong number of edits for %q. want=%d, got=%d", tt.input, len(tt.expected), len(edits))
+			continue
+		}
+		for i, edit := range edits {
+			if edit != tt.expected[i] {
+				t.Errorf("wrong edit for %q. want=%+v, got=%+v", tt.input, tt.expected[i], edit)
+			}
+		}
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	in := fmt.Sprintf("Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: %d\r\n\r\n%s", len(body), body)
+
+	out := serve(t, strings.NewReader(in))
+	if len(out) != 1 || out[0].Error != nil {
+		t.Fatalf("initialize with extra headers failed. got=%+v", out)
+	}
+
+	var discard bytes.Buffer
+	if err := Serve(strings.NewReader("Content-Type: x\r\n\r\n{}"), &discard); err == nil {
+		t.Errorf("expected an error for a message without Content-Length")
+	}
+}
+
+func TestIncompleteSource(t *testing.T) {
+	inputs := []string{
+		"let f = fn(x) { x +",
+		"let = 5; let y",
+		"match (x) { [a",
+		"fn(",
+		"let g = fn(a, b) { return",
+		"let [a, ...",
+		"if (x) { let y = 1; } else",
+		"quote(unquote(",
+		"let m = macro(a) { quote(unquote(a) + ",
+	}
+
+	for _, input := range inputs {
+		var messages []any
+		for character := 0; character <= len(input); character++ {
+			messages = append(messages,
+				"textDocument/hover", position(0, character),
+				"textDocument/definition", position(0, character),
+				"textDocument/completion", position(0, character))
+		}
+		messages = append(messages,
+			"textDocument/documentSymbol", map[string]any{"textDocument": map[string]any{"uri": uri}},
+			"textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": uri}})
+
+		for _, msg := range run(t, input, messages...) {
+			if msg.Error != nil {
+				t.Errorf("error for %q: %s", input, msg.Error.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"monkey-go/lsp"
+	"os"
+)
+
+// runLSP implements `monkey lsp`, a language server speaking LSP over
+// standard input and output.
+func runLSP(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey lsp")
+		return 2
+	}
+
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "monkey lsp: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"monkey-go/repl"
 	"os"
@@ -8,14 +9,48 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:]))
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "lsp":
+			os.Exit(runLSP(os.Args[2:]))
+		case "debug":
+			os.Exit(runDebug(os.Args[2:]))
+		case "dap":
+			os.Exit(runDAP(os.Args[2:]))
+		case "run":
+			os.Exit(runRun(os.Args[2:]))
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
+		}
+	}
+
+	// monkey [-session file] starts the REPL
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	session := flags.String("session", "", "restore the REPL session from `file` and save it there on exit")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey [-session file]\n       monkey fmt|lint|lsp|debug|dap|run|test [arguments]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 
 	fmt.Printf("Hello %s! This is the Monkey programing langage!\n", user.Username)
-	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	fmt.Printf("Feel free to type in commands, or :help\n")
+	repl.StartSession(os.Stdin, os.Stdout, *session)
 	fmt.Printf("Goodbye %s!\n", user.Username)
 	fmt.Printf("See you again!\n")
 }
@@ -1,8 +1,21 @@
 package object
 
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrNotFound   = errors.New("identifier not found")
+	ErrConstant   = errors.New("cannot assign to constant")
+	ErrRedeclared = errors.New("identifier already declared")
+)
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store    map[string]Object
+	consts   map[string]bool
+	outer    *Environment
+	function bool // the scope of a call rather than of a block
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
@@ -11,9 +24,18 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// NewFunctionEnvironment returns the scope of a call of a function defined
+// in outer, where its parameters are bound.
+func NewFunctionEnvironment(outer *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.function = true
+	return env
+}
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s}
+	c := make(map[string]bool)
+	return &Environment{store: s, consts: c}
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -29,12 +51,58 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
-func (e *Environment) Reassign(name string, val Object) (Object, bool) {
+// Declare introduces a new binding in this scope. Bindings in outer scopes
+// may be shadowed, but a name can only be declared once per scope.
+func (e *Environment) Declare(name string, val Object, constant bool) (Object, error) {
+	if _, ok := e.store[name]; ok {
+		return nil, ErrRedeclared
+	}
+
+	e.store[name] = val
+	if constant {
+		e.consts[name] = true
+	}
+	return val, nil
+}
+
+// Reassign updates the nearest existing binding of name.
+// It fails with ErrNotFound if no scope declares name and with ErrConstant if
+// the binding was declared as a constant.
+func (e *Environment) Reassign(name string, val Object) (Object, error) {
 	for env := e; env != nil; env = env.outer {
 		if _, ok := env.store[name]; ok {
+			if env.consts[name] {
+				return nil, ErrConstant
+			}
 			env.store[name] = val
-			return val, true
+			return val, nil
 		}
 	}
-	return nil, false
+	return nil, ErrNotFound
+}
+
+// Names returns the names declared in this scope, not in outer ones, sorted.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Outer returns the enclosing scope, or nil for the outermost one.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// IsFunction reports whether e is the scope of a function call, as opposed
+// to the scope of a block or the outermost one.
+func (e *Environment) IsFunction() bool {
+	return e.function
+}
+
+// IsConst reports whether name is declared as a constant in this scope.
+func (e *Environment) IsConst(name string) bool {
+	return e.consts[name]
 }
@@ -0,0 +1,76 @@
+package object
+
+import "testing"
+
+func TestEnvironmentDeclare(t *testing.T) {
+	env := NewEnvironment()
+
+	if _, err := env.Declare("x", &Integer{Value: 1}, false); err != nil {
+		t.Fatalf("unexpected error declaring x: %s", err)
+	}
+	if _, err := env.Declare("x", &Integer{Value: 2}, false); err != ErrRedeclared {
+		t.Errorf("redeclaring x in same scope. want=%v, got=%v", ErrRedeclared, err)
+	}
+
+	inner := NewEnclosedEnvironment(env)
+	if _, err := inner.Declare("x", &Integer{Value: 3}, false); err != nil {
+		t.Fatalf("shadowing x in enclosed scope failed: %s", err)
+	}
+
+	obj, _ := inner.Get("x")
+	if obj.(*Integer).Value != 3 {
+		t.Errorf("inner x wrong. got=%s", obj.Inspect())
+	}
+	obj, _ = env.Get("x")
+	if obj.(*Integer).Value != 1 {
+		t.Errorf("outer x was modified. got=%s", obj.Inspect())
+	}
+}
+
+func TestEnvironmentReassign(t *testing.T) {
+	env := NewEnvironment()
+	env.Declare("x", &Integer{Value: 1}, false) // nolint
+	env.Declare("c", &Integer{Value: 1}, true)  // nolint
+	inner := NewEnclosedEnvironment(env)
+
+	if _, err := inner.Reassign("x", &Integer{Value: 2}); err != nil {
+		t.Fatalf("unexpected error reassigning x: %s", err)
+	}
+	obj, _ := env.Get("x")
+	if obj.(*Integer).Value != 2 {
+		t.Errorf("x was not reassigned in declaring scope. got=%s", obj.Inspect())
+	}
+
+	if _, err := inner.Reassign("c", &Integer{Value: 2}); err != ErrConstant {
+		t.Errorf("reassigning constant. want=%v, got=%v", ErrConstant, err)
+	}
+	obj, _ = env.Get("c")
+	if obj.(*Integer).Value != 1 {
+		t.Errorf("constant was modified. got=%s", obj.Inspect())
+	}
+
+	if _, err := inner.Reassign("y", &Integer{Value: 2}); err != ErrNotFound {
+		t.Errorf("reassigning undeclared name. want=%v, got=%v", ErrNotFound, err)
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	env := NewEnvironment()
+	env.Declare("b", &Integer{Value: 1}, false) // nolint
+	env.Declare("a", &Integer{Value: 2}, true)  // nolint
+	inner := NewEnclosedEnvironment(env)
+	inner.Declare("c", &Integer{Value: 3}, false) // nolint
+
+	if got := inner.Names(); len(got) != 1 || got[0] != "c" {
+		t.Errorf("inner.Names() wrong. got=%v", got)
+	}
+	if got := env.Names(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("env.Names() wrong. got=%v", got)
+	}
+	if inner.Outer() != env || env.Outer() != nil {
+		t.Errorf("Outer() wrong")
+	}
+	if !env.IsConst("a") || env.IsConst("b") || inner.IsConst("a") {
+		t.Errorf("IsConst wrong")
+	}
+}
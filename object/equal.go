@@ -0,0 +1,61 @@
+package object
+
+// Equal reports whether a and b are structurally equal.
+// Integers, booleans and strings compare by value, arrays element-wise and
+// hashmaps by their key set and the values stored under each key. Any other
+// objects (functions, builtins, null, ...) are equal only when identical.
+//
+// Equal is consistent with HashKey: equal hashable objects have equal keys.
+func Equal(a, b Object) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Array:
+		return arrayEqual(a, b.(*Array))
+	case *HashMap:
+		return hashMapEqual(a, b.(*HashMap))
+	default:
+		return false
+	}
+}
+
+func arrayEqual(a, b *Array) bool {
+	if len(a.Elements) != len(b.Elements) {
+		return false
+	}
+
+	for i := range a.Elements {
+		if !Equal(a.Elements[i], b.Elements[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func hashMapEqual(a, b *HashMap) bool {
+	if len(a.Pairs) != len(b.Pairs) {
+		return false
+	}
+
+	for key, pa := range a.Pairs {
+		pb, ok := b.Pairs[key]
+		if !ok {
+			return false
+		}
+		if !Equal(pa.Key, pb.Key) || !Equal(pa.Value, pb.Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
 	"monkey-go/ast"
@@ -21,6 +22,8 @@ const (
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASHMAP_OBJ      = "HASHMAP"
+	QUOTE_OBJ        = "QUOTE"
+	MACRO_OBJ        = "MACRO"
 )
 
 type Object interface {
@@ -71,15 +74,21 @@ func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Error struct {
 	Message string
+	// Line and Column locate the expression the error was raised in,
+	// 1-based; they are 0 if unknown.
+	Line, Column int
+	// Hint suggests a fix, such as "did you mean `len`?", or is empty.
+	Hint string
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
 type Function struct {
-	Parameters []*ast.Identifier
+	Parameters []ast.Expression
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Evaluated  bool // defined by code run by eval rather than by the program
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -115,17 +124,48 @@ func (s *String) HashKey() HashKey {
 }
 
 type Builtin struct {
-	Fn BuiltinFunction
+	Name string
+	Fn   BuiltinFunction
+	Doc  string // a signature and a one-line description, for tools
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
-func (b *Builtin) Inspect() string  { return "builtin function" }
+func (b *Builtin) Inspect() string {
+	if b.Name == "" {
+		return "builtin function"
+	}
+	return "builtin function " + b.Name
+}
 
 type Array struct {
 	Elements []Object
 }
 
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
+
+// HashKey hashes the elements. It is defined for every array, so that a
+// type assertion to Hashable never panics, but an array with an element
+// that is not hashable, such as a function, is no hashmap key: see
+// IsHashable. Such an element is hashed by its type and Inspect.
+func (ao *Array) HashKey() HashKey {
+	h := fnv.New64a()
+
+	var buf [8]byte
+	for _, e := range ao.Elements {
+		var key HashKey
+		if hashable, ok := e.(Hashable); ok {
+			key = hashable.HashKey()
+		} else {
+			key = (&String{Value: e.Inspect()}).HashKey()
+			key.Type = e.Type()
+		}
+		h.Write([]byte(key.Type))
+		binary.LittleEndian.PutUint64(buf[:], key.Value)
+		h.Write(buf[:])
+	}
+
+	return HashKey{Type: ao.Type(), Value: h.Sum64()}
+}
 func (ao *Array) Inspect() string {
 	var out bytes.Buffer
 
@@ -150,6 +190,23 @@ type Hashable interface {
 	HashKey() HashKey
 }
 
+// IsHashable reports whether obj can be used as a hashmap key.
+// Arrays are treated as immutable tuples and are hashable only when
+// all of their elements are.
+func IsHashable(obj Object) bool {
+	if arr, ok := obj.(*Array); ok {
+		for _, e := range arr.Elements {
+			if !IsHashable(e) {
+				return false
+			}
+		}
+		return true
+	}
+
+	_, ok := obj.(Hashable)
+	return ok
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
@@ -175,3 +232,37 @@ func (h *HashMap) Inspect() string {
 
 	return out.String()
 }
+
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+type Macro struct {
+	Parameters []ast.Expression
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	var params []string
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ","))
+	out.WriteString("){\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
@@ -20,3 +20,93 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestArrayHashKey(t *testing.T) {
+	tuple1 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "one"}}}
+	tuple2 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "one"}}}
+	swapped := &Array{Elements: []Object{&String{Value: "one"}, &Integer{Value: 1}}}
+	nested := &Array{Elements: []Object{&Array{Elements: []Object{&Integer{Value: 1}}}}}
+	flat := &Array{Elements: []Object{&Integer{Value: 1}}}
+
+	if tuple1.HashKey() != tuple2.HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+
+	if tuple1.HashKey() == swapped.HashKey() {
+		t.Errorf("arrays with different order have same hash keys")
+	}
+
+	if nested.HashKey() == flat.HashKey() {
+		t.Errorf("nested array has same hash key as flat array")
+	}
+
+	// not a hashmap key, but hashing it must not panic
+	unhashable := &Array{Elements: []Object{&HashMap{}, &Null{}}}
+	if unhashable.HashKey() != unhashable.HashKey() {
+		t.Errorf("array with unhashable elements has unstable hash keys")
+	}
+}
+
+func TestIsHashable(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, true},
+		{&String{Value: "a"}, true},
+		{&Boolean{Value: true}, true},
+		{&Array{}, true},
+		{&Array{Elements: []Object{&Integer{Value: 1}, &Array{}}}, true},
+		{&Array{Elements: []Object{&HashMap{}}}, false},
+		{&HashMap{}, false},
+		{&Null{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsHashable(tt.obj); got != tt.expected {
+			t.Errorf("IsHashable(%s) wrong. want=%t, got=%t",
+				tt.obj.Inspect(), tt.expected, got)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	one := &Integer{Value: 1}
+	hashMap := func(k, v Object) *HashMap {
+		return &HashMap{Pairs: map[HashKey]HashPair{
+			k.(Hashable).HashKey(): {Key: k, Value: v},
+		}}
+	}
+	fn := &Function{}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Integer{Value: 2}, false},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&String{Value: "1"}, one, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Array{Elements: []Object{one, &String{Value: "x"}}},
+			&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "x"}}}, true},
+		{&Array{Elements: []Object{one}}, &Array{Elements: []Object{one, one}}, false},
+		{&Array{Elements: []Object{&Array{Elements: []Object{one}}}},
+			&Array{Elements: []Object{&Array{Elements: []Object{one}}}}, true},
+		{hashMap(&String{Value: "k"}, one), hashMap(&String{Value: "k"}, &Integer{Value: 1}), true},
+		{hashMap(&String{Value: "k"}, one), hashMap(&String{Value: "k"}, &Integer{Value: 2}), false},
+		{hashMap(&String{Value: "k"}, one), hashMap(&String{Value: "j"}, one), false},
+		{fn, fn, true},
+		{fn, &Function{}, false},
+	}
+
+	for i, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.expected {
+			t.Errorf("tests[%d] - Equal(%s, %s) wrong. want=%t, got=%t",
+				i, tt.a.Inspect(), tt.b.Inspect(), tt.expected, got)
+		}
+		if tt.expected && IsHashable(tt.a) && tt.a.(Hashable).HashKey() != tt.b.(Hashable).HashKey() {
+			t.Errorf("tests[%d] - equal objects have different hash keys", i)
+		}
+	}
+}
@@ -6,33 +6,47 @@ import (
 	"monkey-go/lexer"
 	"monkey-go/token"
 	"strconv"
+	"strings"
 )
 
 const (
 	_ int = iota
 	LOWEST
 	ASSIGN_PREC // =
+	TERNARY     // ? :
 	EQUALS      // ==
 	LESSGREATER // > or <
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
+	POWER       // **
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
 
 var precedencs = map[token.TokenType]int{
-	token.ASSIGN:   ASSIGN_PREC, // =
-	token.EQ:       EQUALS,      // ==
-	token.NOT_EQ:   EQUALS,      // !=
-	token.LT:       LESSGREATER, // <
-	token.GT:       LESSGREATER, // >
-	token.PLUS:     SUM,         // +
-	token.MINUS:    SUM,         // -
-	token.SLASH:    PRODUCT,     // /
-	token.ASTERISK: PRODUCT,     // *
-	token.LPAREN:   CALL,        // (
-	token.LBRACKET: INDEX,       // [
+	token.ASSIGN:    ASSIGN_PREC, // =
+	token.QUESTION:  TERNARY,     // ?
+	token.EQ:        EQUALS,      // ==
+	token.NOT_EQ:    EQUALS,      // !=
+	token.LT:        LESSGREATER, // <
+	token.GT:        LESSGREATER, // >
+	token.PIPE:      BIT_OR,      // |
+	token.CARET:     BIT_XOR,     // ^
+	token.AMPERSAND: BIT_AND,     // &
+	token.LSHIFT:    SHIFT,       // <<
+	token.RSHIFT:    SHIFT,       // >>
+	token.PLUS:      SUM,         // +
+	token.MINUS:     SUM,         // -
+	token.SLASH:     PRODUCT,     // /
+	token.ASTERISK:  PRODUCT,     // *
+	token.POWER:     POWER,       // **
+	token.LPAREN:    CALL,        // (
+	token.LBRACKET:  INDEX,       // [
 }
 
 type (
@@ -43,7 +57,15 @@ type (
 type Parser struct {
 	l *lexer.Lexer
 
-	errors []string
+	errors      []string
+	errorList   []*Error // errors with the position they were found at
+	lexerErrors int      // number of lexer errors already copied into errors
+
+	// panicking is set from an error until the statement it was found in
+	// has been skipped, and keeps the errors that follow from it out of
+	// errors.
+	panicking bool
+	braces    int // number of braces open at curToken
 
 	curToken  token.Token
 	peekToken token.Token
@@ -72,13 +94,18 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashMapLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -89,9 +116,16 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.LSHIFT, p.parseInfixExpression)
+	p.registerInfix(token.RSHIFT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parsePowerExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
 
 	//2つトークンに読み込む。curTokenとpeekTokenの両方がセットされる。
 	p.nextToken()
@@ -100,18 +134,142 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// ErrorKind classifies syntax errors.
+type ErrorKind string
+
+const (
+	// UnexpectedToken is a token other than the Expected one.
+	UnexpectedToken ErrorKind = "unexpected token"
+	// MissingExpression is a token that cannot start an expression where
+	// one is expected.
+	MissingExpression ErrorKind = "missing expression"
+	// InvalidPattern is a token that cannot start a pattern or a hash
+	// pattern key.
+	InvalidPattern ErrorKind = "invalid pattern"
+	// InvalidInteger is an integer literal out of range.
+	InvalidInteger ErrorKind = "invalid integer"
+	// IllegalToken is an error of the lexer, such as an unexpected
+	// character or a malformed literal.
+	IllegalToken ErrorKind = "illegal token"
+)
+
+// Error is a syntax error and the position of the token it was found at.
+type Error struct {
+	Kind     ErrorKind
+	Line     int             // 1-based
+	Column   int             // 1-based, counted in runes
+	Expected token.TokenType // the token that should have come, for UnexpectedToken
+	Found    token.TokenType
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// SyntaxError is the error of a source that does not parse, with the errors
+// found in it.
+type SyntaxError struct {
+	Errors []*Error
+}
+
+// Error returns the messages of the errors, one per line, as Errors does.
+func (e *SyntaxError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Errors returns the messages of the errors found. After an error the
+// parser skips to the end of the statement it was found in, so that every
+// malformed statement is reported once.
 func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// ErrorList returns the errors of Errors, in the same order, with their
+// positions.
+func (p *Parser) ErrorList() []*Error {
+	return p.errorList
+}
+
+// error records err as found at tok, unless it follows from an error of
+// the same statement.
+func (p *Parser) error(tok token.Token, err *Error) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.addError(tok, err)
+}
+
+// addError records err as found at tok, unless the same error was already
+// recorded there. Only the first error at the end of the input is
+// recorded: the others are left by the constructs it ended early.
+func (p *Parser) addError(tok token.Token, err *Error) {
+	err.Line, err.Column, err.Found = tok.Line, tok.Column, tok.Type
+	for _, e := range p.errorList {
+		if e.Line == err.Line && e.Column == err.Column && e.Message == err.Message ||
+			e.Found == token.EOF && err.Found == token.EOF {
+			return
+		}
+	}
+	p.errors = append(p.errors, err.Message)
+	p.errorList = append(p.errorList, err)
+}
+
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead.", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.error(p.peekToken, &Error{Kind: UnexpectedToken, Expected: t, Message: msg})
+}
+
+// synchronize skips the rest of a statement in which an error was found
+// in a block whose opening brace leaves level braces open, or at the top
+// level if level is 0: up to its semicolon, up to the token before the next
+// let, const or return statement or the closing brace of the block, or to
+// the closing brace itself if the statement used it.
+func (p *Parser) synchronize(level int) {
+	p.panicking = false
+	for !p.curTokenIs(token.EOF) && p.braces >= level {
+		if p.braces == level {
+			if p.curTokenIs(token.SEMICOLON) {
+				return
+			}
+			switch p.peekToken.Type {
+			case token.LET, token.CONST, token.RETURN:
+				return
+			case token.RBRACE:
+				if level > 0 {
+					return
+				}
+			}
+		}
+		p.nextToken()
+	}
 }
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braces++
+	case token.RBRACE:
+		if p.braces > 0 {
+			p.braces--
+		}
+	}
+
+	// the lexer reports an error for every ILLEGAL token it returns
+	if errs := p.l.Errors(); len(errs) > p.lexerErrors {
+		for _, msg := range errs[p.lexerErrors:] {
+			p.addError(p.peekToken, &Error{Kind: IllegalToken, Message: msg})
+		}
+		p.lexerErrors = len(errs)
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -119,17 +277,26 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for p.curToken.Type != token.EOF {
-		stmt := p.parseStatement()
-		program.Statements = append(program.Statements, stmt)
+		if stmt := p.parseStatement(); stmt != nil {
+			program.Statements = append(program.Statements, stmt)
+		}
+		if p.panicking {
+			p.synchronize(0)
+		}
 		p.nextToken()
 	}
 	return program
 }
 
+// parseStatement returns the statement at the current token, or nil if it
+// is malformed beyond use. A statement with an error may lack parts.
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
-		return p.parseLetStatement()
+	case token.LET, token.CONST:
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		return p.parseReturnStatement()
 	default:
@@ -140,21 +307,29 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
+	// a statement missing its value still binds its name, for tools
 	if !p.expectPeek(token.ASSIGN) {
-		return nil
+		return stmt
 	}
 
 	p.nextToken()
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	if !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -186,7 +361,7 @@ func (p *Parser) parseReturnStatement() ast.Statement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -211,7 +386,7 @@ func (p *Parser) parseIdentifier() ast.Expression {
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.error(p.curToken, &Error{Kind: MissingExpression, Message: msg})
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
@@ -242,13 +417,19 @@ func (p *Parser) parserIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.error(p.curToken, &Error{Kind: InvalidInteger, Message: msg})
 		return nil
 	}
 	lit.Value = value
 	return lit
 }
 
+// parseIllegal skips an ILLEGAL token. The lexer has already reported why
+// the token is illegal, so no further error is recorded here.
+func (p *Parser) parseIllegal() ast.Expression {
+	return nil
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -270,6 +451,12 @@ func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 	return p.parseInfixExpressionWithPrecedence(left, p.curPrecedence()-1)
 }
 
+// parsePowerExpression parses ** as right-associative, so that 2 ** 3 ** 2
+// groups as 2 ** (3 ** 2).
+func (p *Parser) parsePowerExpression(left ast.Expression) ast.Expression {
+	return p.parseInfixExpressionWithPrecedence(left, p.curPrecedence()-1)
+}
+
 func (p *Parser) parseInfixExpressionWithPrecedence(left ast.Expression, precedence int) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -283,6 +470,15 @@ func (p *Parser) parseInfixExpressionWithPrecedence(left ast.Expression, precede
 	return expression
 }
 
+// Precedence returns the binding power of the infix operator t, or LOWEST if
+// t is not an infix operator.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedencs[t]; ok {
+		return p
+	}
+	return LOWEST
+}
+
 func (p *Parser) peekPrecedence() int {
 	if p, ok := precedencs[p.peekToken.Type]; ok {
 		return p
@@ -301,6 +497,10 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 
@@ -336,6 +536,16 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			elseIf, ok := p.parseIfExpression().(*ast.IfExpression)
+			if !ok {
+				return nil
+			}
+			expression.ElseIf = elseIf
+			return expression
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -347,17 +557,48 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 }
 
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	// parse the alternative one level lower so that `a ? b : c ? d : e`
+	// groups as `a ? b : (c ? d : e)`
+	expression.Alternative = p.parseExpression(TERNARY - 1)
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
+	level := p.braces
 
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		block.Statements = append(block.Statements, stmt)
+		if stmt := p.parseStatement(); stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		if p.panicking {
+			p.synchronize(level)
+			if p.braces < level {
+				break // at the closing brace
+			}
+		}
 		p.nextToken()
 	}
+	if p.curTokenIs(token.EOF) {
+		msg := fmt.Sprintf("expected next token to be %s, got %s instead.", token.RBRACE, token.EOF)
+		p.error(p.curToken, &Error{Kind: UnexpectedToken, Expected: token.RBRACE, Message: msg})
+	}
+	block.Rbrace = p.curToken
 
 	return block
 }
@@ -380,31 +621,149 @@ func (p *Parser) parseFunctionExpression() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	var identifiers []*ast.Identifier
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseBlockStatement()
+
+	return lit
+}
+
+func (p *Parser) parseFunctionParameters() []ast.Expression {
+	var params []ast.Expression
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return params
 	}
 
 	p.nextToken()
-
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	params = append(params, p.parsePattern())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
+		params = append(params, p.parsePattern())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
-	return identifiers
+	return params
+}
+
+// parsePattern parses a binding target: an identifier, a literal, an array
+// pattern like [a, b, ...rest] or a hash pattern like {name, "age": years}.
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return p.parseIdentifier()
+	case token.INT, token.STRING, token.TRUE, token.FALSE, token.NULL:
+		return p.prefixParseFns[p.curToken.Type]()
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) {
+			p.peekError(token.INT)
+			return nil
+		}
+		return p.parsePrefixExpression()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	default:
+		msg := fmt.Sprintf("unexpected %s in pattern", p.curToken.Type)
+		p.error(p.curToken, &Error{Kind: InvalidPattern, Message: msg})
+		return nil
+	}
+}
+
+func (p *Parser) parseArrayPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Expression {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		var pair ast.HashPatternPair
+		switch p.curToken.Type {
+		case token.IDENT:
+			// a bare name is used as a string key: {name} or {name: pattern}
+			pair.Key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+			pair.Value = p.parseIdentifier()
+		case token.STRING, token.INT, token.TRUE, token.FALSE:
+			pair.Key = p.parseExpression(PREFIX)
+			if !p.peekTokenIs(token.COLON) {
+				p.peekError(token.COLON)
+				return nil
+			}
+		default:
+			msg := fmt.Sprintf("unexpected %s as hash pattern key", p.curToken.Type)
+			p.error(p.curToken, &Error{Kind: InvalidPattern, Message: msg})
+			return nil
+		}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			pair.Value = p.parsePattern()
+			if pair.Value == nil {
+				return nil
+			}
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return pattern
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -514,3 +873,67 @@ func (p *Parser) parseHashMapLiteral() ast.Expression {
 
 	return hashMap
 }
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	expression.Rbrace = p.curToken
+
+	return expression
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	pattern := p.parsePattern()
+	if pattern == nil {
+		return nil
+	}
+
+	var guard ast.Expression
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	arm := &ast.MatchArm{Token: p.curToken, Pattern: pattern, Guard: guard}
+
+	p.nextToken()
+	arm.Body = p.parseExpression(LOWEST)
+
+	return arm
+}
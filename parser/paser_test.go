@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"monkey-go/ast"
 	"monkey-go/lexer"
+	"monkey-go/token"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -41,6 +44,254 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	input := "const answer = 42;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	if !stmt.IsConst() {
+		t.Errorf("stmt.IsConst() is false for %q", input)
+	}
+	if stmt.Name.Value != "answer" {
+		t.Errorf("stmt.Name.Value not 'answer'. got=%s", stmt.Name.Value)
+	}
+	testLiteralExpression(t, stmt.Value, 42)
+
+	if stmt.String() != input {
+		t.Errorf("stmt.String() wrong. want=%q, got=%q", input, stmt.String())
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [first, ...rest] = arr;", "let [first, ...rest] = arr;"},
+		{"let [...all] = arr;", "let [...all] = arr;"},
+		{"let [] = arr;", "let [] = arr;"},
+		{"let [a, [b, c]] = arr;", "let [a, [b, c]] = arr;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{`let {"name": n, 1: one} = person;`, "let {name: n, 1: one} = person;"},
+		{"let {name: [first, last]} = person;", "let {name: [first, last]} = person;"},
+		{"const {x, y} = point;", "const {x, y} = point;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Name != nil {
+			t.Errorf("stmt.Name is not nil for destructuring. got=%s", stmt.Name)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestPatternParsingErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, fn] = arr;", "unexpected FUNCTION in pattern"},
+		{"let [-a] = arr;", "expected next token to be INT, got IDENT instead."},
+		{"let [...rest, a] = arr;", "expected next token to be ], got , instead."},
+		{"let [...] = arr;", "expected next token to be IDENT, got ] instead."},
+		{"let {[a]: b} = h;", "unexpected [ as hash pattern key"},
+		{`let {"a"} = h;`, "expected next token to be :, got } instead."},
+		{"fn(if) { 1 }", "unexpected IF in pattern"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedLine   int
+		expectedColumn int
+		expectedMsg    string
+	}{
+		{"let = 5;", 1, 5, "expected next token to be IDENT, got = instead."},
+		{"let x = 1;\nlet y = );", 2, 9, "no prefix parse function for ) found"},
+		{"1 +\n  @", 2, 3, "unexpected character '@'"},
+		{"let [a, fn] = arr;", 1, 9, "unexpected FUNCTION in pattern"},
+		{"add(1, 2", 1, 9, "expected next token to be ), got EOF instead."},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.ErrorList()
+		if len(errs) == 0 || len(errs) != len(p.Errors()) {
+			t.Errorf("wrong number of errors for %q. Errors()=%d, ErrorList()=%d",
+				tt.input, len(p.Errors()), len(errs))
+			continue
+		}
+		err := errs[0]
+		if err.Line != tt.expectedLine || err.Column != tt.expectedColumn {
+			t.Errorf("wrong position for %q. want=%d:%d, got=%d:%d",
+				tt.input, tt.expectedLine, tt.expectedColumn, err.Line, err.Column)
+		}
+		if err.Message != tt.expectedMsg || p.Errors()[0] != tt.expectedMsg {
+			t.Errorf("wrong message for %q. want=%q, got=%q", tt.input, tt.expectedMsg, err.Message)
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     ErrorKind
+		expected token.TokenType
+		found    token.TokenType
+	}{
+		{"let = 5;", UnexpectedToken, token.IDENT, token.ASSIGN},
+		{"let x = );", MissingExpression, "", token.RPAREN},
+		{"let [a, fn] = arr;", InvalidPattern, "", token.FUNCTION},
+		{"0x8000_0000_0000_0000", InvalidInteger, "", token.INT},
+		{"1 + @", IllegalToken, "", token.ILLEGAL},
+		{"fn() { 1", UnexpectedToken, token.RBRACE, token.EOF},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.ErrorList()
+		if len(errs) != 1 {
+			t.Errorf("wrong number of errors for %q. got=%q", tt.input, p.Errors())
+			continue
+		}
+		if errs[0].Kind != tt.kind || errs[0].Expected != tt.expected || errs[0].Found != tt.found {
+			t.Errorf("wrong error for %q. want=%s/%s/%s, got=%s/%s/%s", tt.input,
+				tt.kind, tt.expected, tt.found, errs[0].Kind, errs[0].Expected, errs[0].Found)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		errors   []string // with their positions
+		expected string   // the statements parsed
+	}{
+		{
+			"let = 5; let y = 10; y",
+			[]string{"1:5: expected next token to be IDENT, got = instead."},
+			"let y = 10;y",
+		},
+		{
+			"let x 5;\nlet y = );\ny",
+			[]string{
+				"1:7: expected next token to be =, got INT instead.",
+				"2:9: no prefix parse function for ) found",
+			},
+			"let x = ;let y = ;y",
+		},
+		{
+			"add(1, ; let z = 3;",
+			[]string{"1:8: no prefix parse function for ; found"},
+			"add()let z = 3;",
+		},
+		{
+			"if (x { 1 } let a = 1;",
+			[]string{"1:7: expected next token to be ), got { instead."},
+			"let a = 1;",
+		},
+		{
+			"} let a = 1;",
+			[]string{"1:1: no prefix parse function for } found"},
+			"let a = 1;",
+		},
+		{
+			// blocks recover from errors in their statements
+			"if (x) { let = 1; if (y { 2 } } 4;",
+			[]string{
+				"1:14: expected next token to be IDENT, got = instead.",
+				"1:25: expected next token to be ), got { instead.",
+			},
+			"if (x) {  }4",
+		},
+		{
+			"let f = fn() { let x = }; let z = 3;",
+			[]string{"1:24: no prefix parse function for } found"},
+			"let f = fn()let x = ;;let z = 3;",
+		},
+		{
+			"fn() { let h = {1: }; 2 }; 3",
+			[]string{"1:20: no prefix parse function for } found"},
+			"fn()let h = ;23",
+		},
+		{
+			"let f = fn() { if (x) {",
+			[]string{"1:24: expected next token to be }, got EOF instead."},
+			"let f = fn()if (x) {  };",
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		var errs []string
+		for _, err := range p.ErrorList() {
+			errs = append(errs, err.Error())
+		}
+		if strings.Join(errs, "\n") != strings.Join(tt.errors, "\n") {
+			t.Errorf("wrong errors for %q.\nwant=%q\ngot=%q", tt.input, tt.errors, errs)
+		}
+		for _, stmt := range program.Statements {
+			if stmt == nil || reflect.ValueOf(stmt).IsNil() {
+				t.Errorf("nil statement in %q", tt.input)
+			}
+		}
+		if program.String() != tt.expected {
+			t.Errorf("wrong statements for %q.\nwant=%q\ngot=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -49,6 +300,7 @@ func TestReturnStatements(t *testing.T) {
 		{"return 5;", 5},
 		{"return true;", true},
 		{"return foobar;", "foobar"},
+		{"return 5", 5},
 	}
 
 	for _, tt := range tests {
@@ -71,12 +323,32 @@ func TestReturnStatements(t *testing.T) {
 			t.Fatalf("returnStmt.TokenLiteral not 'return', got %q",
 				returnStmt.TokenLiteral())
 		}
-		if testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
+		if !testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
 			return
 		}
 	}
 }
 
+func TestStatementsWithoutSemicolons(t *testing.T) {
+	input := "let x = 5\nconst y = 6\nreturn x"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{"let x = 5;", "const y = 6;", "return x;"}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d",
+			len(expected), len(program.Statements))
+	}
+	for i, stmt := range program.Statements {
+		if stmt.String() != expected[i] {
+			t.Errorf("stmt %d wrong. want=%q, got=%q", i, expected[i], stmt.String())
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not `let`. got=%q", s.TokenLiteral())
@@ -176,6 +448,69 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0x_dead_beef", 0xdeadbeef},
+		{"0o755", 0o755},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x7FFF_FFFF_FFFF_FFFF", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() does not preserve spelling. want=%q, got=%q",
+				tt.input, literal.String())
+		}
+	}
+}
+
+func TestInvalidIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 0xZZ;", []string{`invalid integer literal "0xZZ": invalid digit 'Z' in hexadecimal literal`}},
+		{"1 + 0b2;", []string{`invalid integer literal "0b2": invalid digit '2' in binary literal`}},
+		{"0x8000_0000_0000_0000", []string{`could not parse "0x8000_0000_0000_0000" as integer`}},
+		{"let y = 5 @ 3;", []string{`unexpected character '@'`}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. want=%q, got=%q", tt.input, tt.expected, errors)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if errors[i] != msg {
+				t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, msg, errors[i])
+			}
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTest := []struct {
 		input        string
@@ -396,6 +731,74 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a < b ? a + 1 : b * 2",
+			"((a < b) ? (a + 1) : (b * 2))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"x = a ? b : c",
+			"(x = (a ? b : c))",
+		},
+		{
+			"f(a ? 1 : 2, b)",
+			"f((a ? 1 : 2), b)",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b == c",
+			"((a & b) == c)",
+		},
+		{
+			"a | b < c",
+			"((a | b) < c)",
+		},
+		{
+			"1 << 2 + 3",
+			"(1 << (2 + 3))",
+		},
+		{
+			"a >> b << c",
+			"((a >> b) << c)",
+		},
+		{
+			"a & b << c",
+			"(a & (b << c))",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"2 * 3 ** 2",
+			"(2 * (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"2 ** -1",
+			"(2 ** (-1))",
+		},
+		{
+			"a ** b[0]",
+			"(a ** (b[0]))",
+		},
 	}
 
 	for i, tt := range tests {
@@ -607,6 +1010,100 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { 0 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	elseIf := exp.ElseIf
+	if elseIf == nil || exp.Alternative != nil {
+		t.Fatalf("exp.ElseIf is nil or exp.Alternative is set. Alternative=%+v", exp.Alternative)
+	}
+
+	if !testInfixExpression(t, elseIf.Condition, "x", ">", "y") {
+		return
+	}
+
+	if elseIf.Alternative == nil || elseIf.ElseIf != nil {
+		t.Fatalf("innermost alternative is not a plain block. got=%+v", elseIf.Alternative)
+	}
+
+	if program.String() != "if (x < y) { x } else if (x > y) { y } else { 0 }" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestIfExpressionString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if (x) { y }", "if (x) { y }"},
+		{"if (x) { y } else { z }", "if (x) { y } else { z }"},
+		{"if (x == 1) { y }", "if (x == 1) { y }"},
+		{"if (!x) { y }", "if (!x) { y }"},
+		{"if (a) { 1 } else if (b) { 2 }", "if (a) { 1 } else if (b) { 2 }"},
+		{"if (a) { 1 } else { if (b) { 2 } }", "if (a) { 1 } else { if (b) { 2 } }"},
+		{"if (a) { 1 } else if (b) { 2 } else if (c) { 3 } else { 4 }",
+			"if (a) { 1 } else if (b) { 2 } else if (c) { 3 } else { 4 }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestConditionalExpression(t *testing.T) {
+	input := `x < y ? x : y`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.ConditionalExpression. got=%T", stmt.Expression)
+	}
+
+	testInfixExpression(t, exp.Condition, "x", "<", "y")
+	testIdentifier(t, exp.Consequence, "x")
+	testIdentifier(t, exp.Alternative, "y")
+
+	l = lexer.New("x ? y")
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be :, got EOF instead." {
+		t.Errorf("missing ':' not reported. got=%q", p.Errors())
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -654,6 +1151,53 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T",
+			stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n",
+			len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n",
+			len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T",
+			macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -684,6 +1228,115 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestFunctionPatternParameters(t *testing.T) {
+	input := "fn([a, ...rest], {name}, c) { a };"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function := stmt.Expression.(*ast.FunctionLiteral)
+
+	if len(function.Parameters) != 3 {
+		t.Fatalf("function literal parameters wrong. want 3, got=%d\n",
+			len(function.Parameters))
+	}
+
+	if _, ok := function.Parameters[0].(*ast.ArrayPattern); !ok {
+		t.Errorf("parameter 0 is not *ast.ArrayPattern. got=%T", function.Parameters[0])
+	}
+	if _, ok := function.Parameters[1].(*ast.HashPattern); !ok {
+		t.Errorf("parameter 1 is not *ast.HashPattern. got=%T", function.Parameters[1])
+	}
+	testIdentifier(t, function.Parameters[2], "c")
+
+	if function.String() != "fn([a, ...rest], {name}, c)a" {
+		t.Errorf("function.String() wrong. got=%q", function.String())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (x) {
+	1 => "one",
+	-1 => "minus one",
+	[a, b] if a > b => a,
+	{"type": "t", value} => value,
+	_ => 0,
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Subject, "x") {
+		return
+	}
+
+	if len(exp.Arms) != 5 {
+		t.Fatalf("exp.Arms does not contain 5 arms. got=%d", len(exp.Arms))
+	}
+
+	testIntegerLiteral(t, exp.Arms[0].Pattern, 1)
+	if exp.Arms[0].Guard != nil {
+		t.Errorf("exp.Arms[0].Guard is not nil. got=%s", exp.Arms[0].Guard)
+	}
+	if _, ok := exp.Arms[2].Pattern.(*ast.ArrayPattern); !ok {
+		t.Errorf("exp.Arms[2].Pattern is not ast.ArrayPattern. got=%T", exp.Arms[2].Pattern)
+	}
+	testInfixExpression(t, exp.Arms[2].Guard, "a", ">", "b")
+	testIdentifier(t, exp.Arms[2].Body, "a")
+	if _, ok := exp.Arms[3].Pattern.(*ast.HashPattern); !ok {
+		t.Errorf("exp.Arms[3].Pattern is not ast.HashPattern. got=%T", exp.Arms[3].Pattern)
+	}
+	testIdentifier(t, exp.Arms[4].Pattern, "_")
+
+	expected := `match (x) { 1 => one, (-1) => minus one, [a, b] if (a > b) => a, {type: t, value} => value, _ => 0 }`
+	if exp.String() != expected {
+		t.Errorf("exp.String() wrong.\nwant=%q\ngot= %q", expected, exp.String())
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { _ => 1 }", "expected next token to be (, got IDENT instead."},
+		{"match (x) { 1 -> 1 }", "expected next token to be =>, got - instead."},
+		{"match (x) { 1 => 1 2 => 2 }", "expected next token to be ,, got INT instead."},
+		{"match (x) { + => 1 }", "unexpected + in pattern"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
 func TestCallExpression(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -737,6 +1390,24 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestNullLiteral(t *testing.T) {
+	p := New(lexer.New("let x = null; match (x) { null => 0 }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	if _, ok := let.Value.(*ast.NullLiteral); !ok {
+		t.Fatalf("let.Value not *ast.NullLiteral. got=%T", let.Value)
+	}
+	match := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	if _, ok := match.Arms[0].Pattern.(*ast.NullLiteral); !ok {
+		t.Errorf("pattern not *ast.NullLiteral. got=%T", match.Arms[0].Pattern)
+	}
+	if program.String() != "let x = null;match (x) { null => 0 }" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
 func TestParsingEmptyArrayLiterals(t *testing.T) {
 	input := "[]"
 
@@ -0,0 +1,194 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The pprof format is the protocol buffer message Profile of
+// github.com/google/pprof/proto/profile.proto, compressed with gzip. The
+// few messages needed are encoded by hand; the field numbers below are
+// those of profile.proto.
+
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileTimeNanos         = 9
+	profileDurationNanos     = 10
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// sampleTypes are the values of every sample, in order.
+var sampleTypes = [][2]string{
+	{"calls", "count"},
+	{"time", "nanoseconds"},
+	{"alloc_space", "bytes"},
+	{"alloc_objects", "count"},
+}
+
+// WritePprof writes the measurements as a pprof profile. Each sample is a
+// stack of Monkey calls with the calls, time and allocations of its
+// innermost function, so pprof can show both self and cumulative values.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	var b encoder
+	index := map[string]int64{}
+	str := func(s string) int64 {
+		i, ok := index[s]
+		if !ok {
+			i = int64(len(index))
+			index[s] = i
+		}
+		return i
+	}
+	str("")
+
+	for _, t := range sampleTypes {
+		b.message(profileSampleType, func(b *encoder) {
+			b.int64(valueTypeType, str(t[0]))
+			b.int64(valueTypeUnit, str(t[1]))
+		})
+	}
+
+	// samples in a stable order, and a function and location per Func
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ids := map[Func]uint64{}
+	var funcs []Func
+	for _, key := range keys {
+		s := p.samples[key]
+		locations := make([]uint64, len(s.stack))
+		for i, f := range s.stack {
+			if ids[f] == 0 {
+				funcs = append(funcs, f)
+				ids[f] = uint64(len(funcs))
+			}
+			locations[i] = ids[f]
+		}
+		b.message(profileSample, func(b *encoder) {
+			b.packed(sampleLocationID, locations)
+			b.packed(sampleValue, []uint64{
+				uint64(s.calls), uint64(s.time), uint64(s.bytes), uint64(s.allocs),
+			})
+		})
+	}
+
+	for i, f := range funcs {
+		id := uint64(i + 1)
+		b.message(profileLocation, func(b *encoder) {
+			b.uint64(locationID, id)
+			b.message(locationLine, func(b *encoder) {
+				b.uint64(lineFunctionID, id)
+				b.int64(lineLine, int64(f.Line))
+			})
+		})
+	}
+	for i, f := range funcs {
+		id := uint64(i + 1)
+		// pprof drops <...> from names as if they were C++ templates
+		name := strings.Trim(f.Name, "<>")
+		b.message(profileFunction, func(b *encoder) {
+			b.uint64(functionID, id)
+			b.int64(functionName, str(name))
+			b.int64(functionSystemName, str(name))
+			b.int64(functionFilename, str(p.file))
+			b.int64(functionStartLine, int64(f.Line))
+		})
+	}
+
+	b.int64(profileTimeNanos, p.start.UnixNano())
+	b.int64(profileDurationNanos, int64(p.duration))
+	b.int64(profileDefaultSampleType, str("time"))
+
+	table := make([]string, len(index))
+	for s, i := range index {
+		table[i] = s
+	}
+	for _, s := range table {
+		b.string(profileStringTable, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// encoder appends protocol buffer fields to buf.
+type encoder struct {
+	buf []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *encoder) varint(x uint64) {
+	for x >= 0x80 {
+		b.buf = append(b.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	b.buf = append(b.buf, byte(x))
+}
+
+func (b *encoder) key(field, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *encoder) uint64(field int, x uint64) {
+	b.key(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *encoder) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *encoder) string(field int, s string) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(s)))
+	b.buf = append(b.buf, s...)
+}
+
+func (b *encoder) packed(field int, xs []uint64) {
+	var inner encoder
+	for _, x := range xs {
+		inner.varint(x)
+	}
+	b.string(field, string(inner.buf))
+}
+
+// message encodes a nested message with fields.
+func (b *encoder) message(field int, fields func(*encoder)) {
+	var inner encoder
+	fields(&inner)
+	b.string(field, string(inner.buf))
+}
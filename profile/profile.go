@@ -0,0 +1,363 @@
+// Package profile measures where a Monkey program spends its time. A
+// Profiler follows the calls of Monkey functions through the evaluator
+// hook and records, per function, how often it is called, the time spent
+// in it and the memory it allocates. The results can be written as a table
+// or as a pprof profile for `go tool pprof`. It is used by
+// `monkey run -profile`.
+package profile
+
+import (
+	"fmt"
+	"io"
+	"monkey-go/ast"
+	"monkey-go/evaluator"
+	"monkey-go/object"
+	"runtime/metrics"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Func identifies a Monkey function by the name it is bound to and the
+// position of its `fn` keyword. Functions that are not bound by a let or
+// const statement are called "<anonymous>". The program itself is the
+// function "<program>" at line 0.
+type Func struct {
+	Name         string
+	Line, Column int
+}
+
+func (f Func) String() string {
+	if f.Line == 0 {
+		return f.Name
+	}
+	return fmt.Sprintf("%s %d:%d", f.Name, f.Line, f.Column)
+}
+
+// programFunc stands for the statements outside any function.
+var programFunc = Func{Name: "<program>"}
+
+// Stats are the measurements of one function. Self counts exclude the
+// functions it calls; Total includes them and counts the time of
+// recursive calls once.
+type Stats struct {
+	Func
+	Calls      int
+	Total      time.Duration
+	Self       time.Duration
+	SelfBytes  int64 // bytes allocated
+	SelfAllocs int64 // objects allocated
+}
+
+// sample is what was measured with stack as the calls being run,
+// innermost first.
+type sample struct {
+	stack  []Func
+	calls  int64
+	time   time.Duration
+	bytes  int64
+	allocs int64
+}
+
+// reading is the clock and the allocation counters at one moment.
+type reading struct {
+	time          time.Time
+	bytes, allocs int64
+}
+
+// usage is what was used between two readings.
+type usage struct {
+	time          time.Duration
+	bytes, allocs int64
+}
+
+func (r reading) since(start reading) usage {
+	return usage{r.time.Sub(start.time), r.bytes - start.bytes, r.allocs - start.allocs}
+}
+
+func (u usage) add(v usage) usage {
+	return usage{u.time + v.time, u.bytes + v.bytes, u.allocs + v.allocs}
+}
+
+func (u usage) sub(v usage) usage {
+	return usage{u.time - v.time, u.bytes - v.bytes, u.allocs - v.allocs}
+}
+
+// frame is a call being run. Calls in tail position continue the frame of
+// the call they replace.
+type frame struct {
+	fn       Func
+	calls    int     // of fn in the frame, more than one after tail calls
+	start    reading // when fn started running in the frame
+	overhead usage   // the profiler's overhead then
+	child    usage   // of the calls fn made
+	replaced usage   // of the functions fn replaced
+}
+
+// A Profiler measures one run of a program. It implements evaluator.Hook.
+// Allocations are those counted by the Go runtime while the function runs,
+// so they include the work of the interpreter itself, but not the work of
+// the profiler.
+type Profiler struct {
+	file  string
+	funcs map[position]Func // by the position of the function body
+
+	stack    []*frame
+	tail     bool         // the innermost call is being replaced by a tail call
+	active   map[Func]int // the calls of each function being run
+	stats    map[Func]*Stats
+	samples  map[string]*sample
+	overhead usage // of the profiler itself, so far
+
+	start    time.Time
+	duration time.Duration
+
+	// clock and counters are replaced in tests.
+	clock    func() time.Time
+	counters func() (bytes, allocs int64)
+}
+
+type position struct{ line, column int }
+
+// New returns a profiler for program, the source of file. Functions are
+// named after the let and const statements of program binding them.
+func New(file string, program ast.Node) *Profiler {
+	p := &Profiler{
+		file:     file,
+		funcs:    map[position]Func{},
+		active:   map[Func]int{},
+		stats:    map[Func]*Stats{},
+		samples:  map[string]*sample{},
+		clock:    time.Now,
+		counters: readCounters,
+	}
+
+	// Modify visits a function literal before the statement binding it.
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			p.funcs[bodyPosition(node.Body)] = Func{
+				Name:   "<anonymous>",
+				Line:   node.Token.Line,
+				Column: node.Token.Column,
+			}
+		case *ast.LetStatement:
+			if fl, ok := node.Value.(*ast.FunctionLiteral); ok && node.Name != nil {
+				pos := bodyPosition(fl.Body)
+				f := p.funcs[pos]
+				f.Name = node.Name.Value
+				p.funcs[pos] = f
+			}
+		}
+		return node
+	})
+	return p
+}
+
+func bodyPosition(body *ast.BlockStatement) position {
+	return position{body.Token.Line, body.Token.Column}
+}
+
+var counterSamples = []metrics.Sample{
+	{Name: "/gc/heap/allocs:bytes"},
+	{Name: "/gc/heap/allocs:objects"},
+}
+
+func readCounters() (bytes, allocs int64) {
+	metrics.Read(counterSamples)
+	return int64(counterSamples[0].Value.Uint64()), int64(counterSamples[1].Value.Uint64())
+}
+
+// Run evaluates program in env under the profiler and returns its result.
+func (p *Profiler) Run(program ast.Node, env *object.Environment) object.Object {
+	old := evaluator.SetHook(p)
+	defer evaluator.SetHook(old)
+
+	at := p.read()
+	p.start = at.time
+	p.push(programFunc, at)
+	p.account(at)
+	result := evaluator.Eval(program, env)
+	at = p.read()
+	for len(p.stack) > 0 {
+		p.pop(at)
+	}
+	p.duration = at.time.Sub(p.start) - p.overhead.time
+	return result
+}
+
+func (p *Profiler) Statement(stmt ast.Statement, env *object.Environment) *object.Error {
+	return nil
+}
+
+func (p *Profiler) Enter(call *ast.CallExpression, fn *object.Function, env *object.Environment) {
+	at := p.read()
+	f, ok := p.funcs[bodyPosition(fn.Body)]
+	if !ok {
+		// not defined in the program, e.g. quoted code
+		f = Func{Name: "<anonymous>", Line: fn.Body.Token.Line, Column: fn.Body.Token.Column}
+	}
+	if p.tail {
+		p.replace(f, at)
+	} else {
+		p.push(f, at)
+	}
+	p.account(at)
+}
+
+func (p *Profiler) Leave(call *ast.CallExpression, fn *object.Function, result object.Object) {
+	if result == nil {
+		// the call goes on in the frame, the time until Enter is its own
+		p.tail = true
+		return
+	}
+	at := p.read()
+	p.pop(at)
+	p.account(at)
+}
+
+func (p *Profiler) read() reading {
+	bytes, allocs := p.counters()
+	return reading{p.clock(), bytes, allocs}
+}
+
+// account adds the work of the profiler since at to its overhead, which is
+// charged to no function.
+func (p *Profiler) account(at reading) {
+	p.overhead = p.overhead.add(p.read().since(at))
+}
+
+func (p *Profiler) push(f Func, at reading) {
+	p.stack = append(p.stack, &frame{fn: f, calls: 1, start: at, overhead: p.overhead})
+	p.active[f]++
+}
+
+// used returns what the innermost frame used since its function started
+// running in it, without the overhead of the profiler.
+func (p *Profiler) used(at reading) usage {
+	fr := p.stack[len(p.stack)-1]
+	return at.since(fr.start).sub(p.overhead.sub(fr.overhead))
+}
+
+// replace continues the innermost frame with a tail call of f.
+func (p *Profiler) replace(f Func, at reading) {
+	p.tail = false
+	fr := p.stack[len(p.stack)-1]
+	if fr.fn == f {
+		fr.calls++
+		return
+	}
+	used := p.used(at)
+	p.finish(used)
+	p.active[f]++
+	fr.fn, fr.calls, fr.start, fr.overhead = f, 1, at, p.overhead
+	fr.child, fr.replaced = usage{}, fr.replaced.add(used)
+}
+
+// pop ends the innermost call and charges what it used to its caller.
+func (p *Profiler) pop(at reading) {
+	p.tail = false
+	used := p.used(at)
+	p.finish(used)
+	fr := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	if len(p.stack) > 0 {
+		parent := p.stack[len(p.stack)-1]
+		parent.child = parent.child.add(used).add(fr.replaced)
+	}
+}
+
+// finish records the measurements of the function of the innermost frame,
+// which used used.
+func (p *Profiler) finish(used usage) {
+	fr := p.stack[len(p.stack)-1]
+	self := used.sub(fr.child)
+
+	s := p.stats[fr.fn]
+	if s == nil {
+		s = &Stats{Func: fr.fn}
+		p.stats[fr.fn] = s
+	}
+	s.Calls += fr.calls
+	s.Self += self.time
+	s.SelfBytes += self.bytes
+	s.SelfAllocs += self.allocs
+	p.active[fr.fn]--
+	if p.active[fr.fn] == 0 {
+		s.Total += used.time
+	}
+
+	stack := make([]Func, len(p.stack))
+	keys := make([]string, len(p.stack))
+	for i := range p.stack {
+		stack[i] = p.stack[len(p.stack)-1-i].fn
+		keys[i] = stack[i].String()
+	}
+	key := strings.Join(keys, "\n")
+	smp := p.samples[key]
+	if smp == nil {
+		smp = &sample{stack: stack}
+		p.samples[key] = smp
+	}
+	smp.calls += int64(fr.calls)
+	smp.time += self.time
+	smp.bytes += self.bytes
+	smp.allocs += self.allocs
+}
+
+// Stats returns the measurements of every function called, the most
+// expensive first.
+func (p *Profiler) Stats() []Stats {
+	stats := make([]Stats, 0, len(p.stats))
+	for _, s := range p.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Self != stats[j].Self {
+			return stats[i].Self > stats[j].Self
+		}
+		if stats[i].Line != stats[j].Line {
+			return stats[i].Line < stats[j].Line
+		}
+		return stats[i].Column < stats[j].Column
+	})
+	return stats
+}
+
+// WriteTable writes the measurements as a table, the most expensive
+// function first.
+func (p *Profiler) WriteTable(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%7s %10s %10s %8s %10s %8s  %s\n",
+		"self%", "self", "total", "calls", "alloc", "allocs", "function"); err != nil {
+		return err
+	}
+	for _, s := range p.Stats() {
+		percent := 0.0
+		if p.duration > 0 {
+			percent = 100 * float64(s.Self) / float64(p.duration)
+		}
+		name := s.Func.String()
+		if s.Line != 0 {
+			name = fmt.Sprintf("%s %s:%d:%d", s.Name, p.file, s.Line, s.Column)
+		}
+		if _, err := fmt.Fprintf(w, "%6.2f%% %10s %10s %8d %10s %8d  %s\n",
+			percent, duration(s.Self), duration(s.Total), s.Calls, size(s.SelfBytes), s.SelfAllocs, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func duration(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d)/float64(time.Millisecond))
+}
+
+func size(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fkB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -0,0 +1,241 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const input = `let double = fn(x) { x * 2 };
+let twice = fn(f, x) { f(f(x)) };
+twice(double, 1);
+fn(y) { y }(5)`
+
+// profile runs input with a clock that advances a millisecond and counters
+// that advance 10 bytes and one object every time they are read. As the
+// profiler reads them at the start and the end of every event, the program
+// takes a millisecond between two events and the profiler one per event.
+func profile(t *testing.T, input string) *Profiler {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	prof := New("test.monkey", program)
+	var now time.Time
+	prof.clock = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	var count int64
+	prof.counters = func() (int64, int64) {
+		count++
+		return 10 * count, count
+	}
+
+	if result := prof.Run(program, object.NewEnvironment()); result.Inspect() != "5" {
+		t.Fatalf("wrong result. got=%s", result.Inspect())
+	}
+	return prof
+}
+
+func TestStats(t *testing.T) {
+	prof := profile(t, input)
+
+	// the tail call of f in twice replaces twice
+	expected := []Stats{
+		{Func{"<program>", 0, 0}, 1, 8 * time.Millisecond, 3 * time.Millisecond, 30, 3},
+		{Func{"double", 1, 14}, 2, 2 * time.Millisecond, 2 * time.Millisecond, 20, 2},
+		{Func{"twice", 2, 13}, 1, 3 * time.Millisecond, 2 * time.Millisecond, 20, 2},
+		{Func{"<anonymous>", 4, 1}, 1, time.Millisecond, time.Millisecond, 10, 1},
+	}
+	stats := prof.Stats()
+	if len(stats) != len(expected) {
+		t.Fatalf("wrong number of functions. want=%d, got=%d (%+v)", len(expected), len(stats), stats)
+	}
+	for i, s := range stats {
+		if s != expected[i] {
+			t.Errorf("stats[%d] wrong.\nwant=%+v\ngot=%+v", i, expected[i], s)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := prof.WriteTable(&out); err != nil {
+		t.Fatalf("WriteTable: %v", err)
+	}
+	table := `  self%       self      total    calls      alloc   allocs  function
+ 37.50%    3.000ms    8.000ms        1        30B        3  <program>
+ 25.00%    2.000ms    2.000ms        2        20B        2  double test.monkey:1:14
+ 25.00%    2.000ms    3.000ms        1        20B        2  twice test.monkey:2:13
+ 12.50%    1.000ms    1.000ms        1        10B        1  <anonymous> test.monkey:4:1
+`
+	if out.String() != table {
+		t.Errorf("wrong table.\nwant=\n%s\ngot=\n%s", table, out.String())
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	prof := profile(t, `let count = fn(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } };
+count(2) + 3`)
+
+	for _, s := range prof.Stats() {
+		if s.Name != "count" {
+			continue
+		}
+		// count(2) runs for 5 of the 10 milliseconds between its Enter and
+		// Leave, the others are the profiler's; the nested calls are not
+		// counted again in the total
+		if s.Calls != 3 || s.Total != 5*time.Millisecond || s.Self != 5*time.Millisecond {
+			t.Errorf("wrong stats for count. got=%+v", s)
+		}
+		return
+	}
+	t.Fatalf("count not profiled")
+}
+
+func TestTailRecursion(t *testing.T) {
+	prof := profile(t, `let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } };
+count(3, 0) + 2`)
+
+	// the tail calls continue the first call of count, which is charged to
+	// the program once with the time between the calls
+	expected := []Stats{
+		{Func{"count", 1, 13}, 4, 4 * time.Millisecond, 4 * time.Millisecond, 40, 4},
+		{Func{"<program>", 0, 0}, 1, 6 * time.Millisecond, 2 * time.Millisecond, 20, 2},
+	}
+	stats := prof.Stats()
+	if len(stats) != len(expected) {
+		t.Fatalf("wrong number of functions. want=%d, got=%d (%+v)", len(expected), len(stats), stats)
+	}
+	for i, s := range stats {
+		if s != expected[i] {
+			t.Errorf("stats[%d] wrong.\nwant=%+v\ngot=%+v", i, expected[i], s)
+		}
+	}
+}
+
+// field is a decoded protocol buffer field: a varint or bytes.
+type field struct {
+	num    int
+	varint uint64
+	bytes  []byte
+}
+
+func decode(t *testing.T, buf []byte) []field {
+	t.Helper()
+
+	varint := func() uint64 {
+		var x uint64
+		for shift := 0; ; shift += 7 {
+			if len(buf) == 0 {
+				t.Fatalf("truncated varint")
+			}
+			b := buf[0]
+			buf = buf[1:]
+			x |= uint64(b&0x7f) << shift
+			if b < 0x80 {
+				return x
+			}
+		}
+	}
+
+	var fields []field
+	for len(buf) > 0 {
+		key := varint()
+		f := field{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			f.varint = varint()
+		case wireBytes:
+			n := varint()
+			f.bytes, buf = buf[:n], buf[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func TestWritePprof(t *testing.T) {
+	prof := profile(t, input)
+
+	var out bytes.Buffer
+	if err := prof.WritePprof(&out); err != nil {
+		t.Fatalf("WritePprof: %v", err)
+	}
+	zr, err := gzip.NewReader(&out)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	buf, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading profile: %v", err)
+	}
+
+	var strs []string
+	var samples [][]field
+	names := map[uint64]uint64{} // function ID to name
+	for _, f := range decode(t, buf) {
+		switch f.num {
+		case profileStringTable:
+			strs = append(strs, string(f.bytes))
+		case profileSample:
+			samples = append(samples, decode(t, f.bytes))
+		case profileFunction:
+			var id, name uint64
+			for _, ff := range decode(t, f.bytes) {
+				switch ff.num {
+				case functionID:
+					id = ff.varint
+				case functionName:
+					name = ff.varint
+				}
+			}
+			names[id] = name
+		}
+	}
+
+	// samples are the stacks of calls with their values, e.g.
+	// "double twice program: 1 1000000 10 1"
+	var got []string
+	for _, sample := range samples {
+		var stack, values []string
+		for _, f := range sample {
+			for rest := f.bytes; len(rest) > 0; {
+				x, n := binary.Uvarint(rest)
+				rest = rest[n:]
+				if f.num == sampleLocationID {
+					stack = append(stack, strs[names[x]])
+				} else {
+					values = append(values, strconv.FormatUint(x, 10))
+				}
+			}
+		}
+		got = append(got, strings.Join(stack, " ")+": "+strings.Join(values, " "))
+	}
+	sort.Strings(got)
+
+	expected := []string{
+		"anonymous program: 1 1000000 10 1",
+		"double program: 1 1000000 10 1",
+		"double twice program: 1 1000000 10 1",
+		"program: 1 3000000 30 3",
+		"twice program: 1 2000000 20 2",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong samples.\nwant=%q\ngot=%q", expected, got)
+	}
+}
@@ -0,0 +1,263 @@
+package repl
+
+import (
+	"fmt"
+	"monkey-go/ast"
+	"monkey-go/diagnostic"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"monkey-go/token"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// command is a REPL command, entered as a colon, its name and its argument.
+type command struct {
+	name string
+	arg  string // the argument in :help, empty if it takes none
+	help string
+	run  func(s *session, arg string)
+}
+
+// commands is set in init, as :help lists it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"tokens", "<src>", "show the tokens of src", (*session).tokens},
+		{"ast", "<src>", "show the syntax tree of src", (*session).ast},
+		{"env", "", "list the bindings of the session", (*session).bindings},
+		{"type", "<expr>", "show the type of the value of expr", (*session).typeOf},
+		{"load", "<file>", "evaluate the program in file in the session", (*session).loadCommand},
+		{"reset", "", "forget all bindings", (*session).resetCommand},
+		{"save", "<file>", "write the inputs of the session to file", (*session).saveCommand},
+		{"restore", "<file>", "start over with the inputs saved in file", (*session).restoreCommand},
+		{"time", "<expr>", "evaluate expr and show how long it took", (*session).time},
+		{"help", "", "list the commands", (*session).help},
+	}
+}
+
+// command runs line, a colon followed by the name of a command and its
+// argument.
+func (s *session) command(line string) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), ":"), " ")
+	arg = strings.TrimSpace(arg)
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if c.arg != "" && arg == "" {
+			fmt.Fprintf(s.out, "usage: :%s %s\n", c.name, c.arg) // nolint
+			return
+		}
+		if c.arg == "" && arg != "" {
+			fmt.Fprintf(s.out, ":%s takes no argument\n", c.name) // nolint
+			return
+		}
+		c.run(s, arg)
+		return
+	}
+	fmt.Fprintf(s.out, "unknown command :%s, :help lists the commands\n", name) // nolint
+}
+
+func (s *session) tokens(src string) {
+	w := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	l := lexer.New(src)
+	for {
+		tok := l.NextToken()
+		fmt.Fprintf(w, "%d:%d\t%s\t%q\n", tok.Line, tok.Column, tok.Type, tok.Literal) // nolint
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	w.Flush() // nolint
+}
+
+// ast shows the tree src is parsed to. src is not a line of the session, so
+// errors are shown at their position in src.
+func (s *session) ast(src string) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printer := &diagnostic.Printer{File: "repl", Source: src, Color: s.printer.Color}
+		printParserErrors(s.out, printer, p.ErrorList())
+		return
+	}
+	printTree(s.out, program)
+}
+
+// bindings lists the names bound in the session, and its macros, with their
+// values.
+func (s *session) bindings(string) {
+	for _, env := range []*object.Environment{s.env, s.macroEnv} {
+		for _, name := range env.Names() {
+			value, _ := env.Get(name)
+			keyword := "let"
+			if env.IsConst(name) {
+				keyword = "const"
+			}
+			fmt.Fprintf(s.out, "%s %s = %s\n", keyword, name, value.Inspect()) // nolint
+		}
+	}
+}
+
+// typeOf evaluates expr in a scope of its own, so that bindings it makes
+// are dropped, and shows the type of its value.
+func (s *session) typeOf(expr string) {
+	program := s.parse(expr)
+	if program == nil {
+		return
+	}
+	if evaluated, ok := s.run(program, object.NewEnclosedEnvironment(s.env), s.printer); ok && evaluated != nil {
+		fmt.Fprintln(s.out, evaluated.Type()) // nolint
+	}
+}
+
+func (s *session) loadCommand(path string) {
+	s.load(path) // nolint
+}
+
+// load evaluates the program in path as if its lines were entered. Errors
+// are shown at their position in the file, and false is returned for them.
+// Errors found later in the functions it defines are shown at the lines the
+// file adds to the session.
+func (s *session) load(path string) bool {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(s.out, err) // nolint
+		return false
+	}
+	printer := &diagnostic.Printer{File: path, Source: string(src), Color: s.printer.Color, Offset: len(s.lines)}
+
+	p := parser.New(lexer.New(s.addLines(strings.TrimSuffix(string(src), "\n"))))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, printer, p.ErrorList())
+		return false
+	}
+	s.history = append(s.history, strings.TrimSuffix(string(src), "\n"))
+	_, ok := s.run(program, s.env, printer)
+	return ok
+}
+
+func (s *session) resetCommand(string) {
+	s.reset()
+}
+
+func (s *session) saveCommand(path string) {
+	if err := s.save(path); err != nil {
+		fmt.Fprintln(s.out, err) // nolint
+	}
+}
+
+// inputSeparator is the line that ends every input in a file written by
+// :save. It also ends a statement the input leaves open, so that the next
+// input cannot continue it, e.g. as the arguments of a call.
+const inputSeparator = ";"
+
+// save writes the history of the session to path as a program that
+// :restore evaluates to the same bindings.
+func (s *session) save(path string) error {
+	var out strings.Builder
+	for _, input := range s.history {
+		out.WriteString(input + "\n" + inputSeparator + "\n")
+	}
+	return os.WriteFile(path, []byte(out.String()), 0o644)
+}
+
+func (s *session) restoreCommand(path string) {
+	s.restore(path) // nolint
+}
+
+// restore starts the session over with the inputs saved in path by :save.
+// Each input is run on its own, as it was entered, so an error ends only
+// its input; errors are shown at their position in the file. If path
+// cannot be read or parsed, false is returned and the session is kept as
+// it was.
+func (s *session) restore(path string) bool {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(s.out, err) // nolint
+		return false
+	}
+
+	// the inputs are added to the lines of the session as if they were
+	// entered, their errors are shown at their lines in the file
+	restored := newSession(s.out)
+	restored.printer.Color = s.printer.Color
+	var programs []*ast.Program
+	var printers []*diagnostic.Printer
+	for _, input := range splitInputs(string(src)) {
+		printer := &diagnostic.Printer{
+			File:   path,
+			Source: string(src),
+			Color:  s.printer.Color,
+			Offset: len(restored.lines) - (input.line - 1),
+		}
+		p := parser.New(lexer.New(restored.addLines(input.src)))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			printParserErrors(s.out, printer, p.ErrorList())
+			return false
+		}
+		programs = append(programs, program)
+		printers = append(printers, printer)
+		restored.history = append(restored.history, input.src)
+	}
+	for i, program := range programs {
+		restored.run(program, restored.env, printers[i])
+	}
+	*s = *restored
+	return true
+}
+
+// savedInput is an input of a file written by :save and the line it starts
+// at.
+type savedInput struct {
+	src  string
+	line int
+}
+
+// splitInputs returns the inputs of src, which are ended by separator
+// lines. The text after the last separator is an input too, so that a
+// program written by hand is a single input.
+func splitInputs(src string) []savedInput {
+	var inputs []savedInput
+	var lines []string
+	start := 1
+	for i, line := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+		if line != inputSeparator {
+			lines = append(lines, line)
+			continue
+		}
+		if input := strings.Join(lines, "\n"); strings.TrimSpace(input) != "" {
+			inputs = append(inputs, savedInput{input, start})
+		}
+		lines, start = nil, i+2
+	}
+	if input := strings.Join(lines, "\n"); strings.TrimSpace(input) != "" {
+		inputs = append(inputs, savedInput{input, start})
+	}
+	return inputs
+}
+
+func (s *session) time(expr string) {
+	start := time.Now()
+	if ok, err := s.eval(expr); !ok || err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "took %s\n", time.Since(start)) // nolint
+}
+
+func (s *session) help(string) {
+	w := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	for _, c := range commands {
+		usage := strings.TrimSpace(":" + c.name + " " + c.arg)
+		fmt.Fprintf(w, "%s\t%s\n", usage, c.help) // nolint
+	}
+	fmt.Fprintf(w, "exit\tleave the REPL\n") // nolint
+	w.Flush()                                // nolint
+}
@@ -0,0 +1,79 @@
+package repl
+
+import (
+	"monkey-go/evaluator"
+	"monkey-go/object"
+	"monkey-go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// complete returns the words the word that ends at cursor in line can be
+// completed to, sorted, and the offset where the word starts. Words are
+// keywords, builtin names and the names bound in the session, or commands
+// at the start of a line.
+func (s *session) complete(line []rune, cursor int) (int, []string) {
+	start := cursor
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+	word := string(line[start:cursor])
+
+	if before := strings.TrimSpace(string(line[:start])); before == ":" {
+		var names []string
+		for _, c := range commands {
+			names = append(names, c.name)
+		}
+		return start, withPrefix(names, word)
+	}
+	if word == "" {
+		return start, nil
+	}
+
+	names := append(token.Keywords(), evaluator.BuiltinNames()...)
+	for _, env := range []*object.Environment{s.env, s.macroEnv} {
+		for ; env != nil; env = env.Outer() {
+			names = append(names, env.Names()...)
+		}
+	}
+	return start, withPrefix(names, word)
+}
+
+// withPrefix returns the names that start with prefix, sorted and without
+// duplicates.
+func withPrefix(names []string, prefix string) []string {
+	seen := map[string]bool{}
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// commonPrefix returns the longest prefix of all words.
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := []rune(words[0])
+	for _, word := range words[1:] {
+		w := []rune(word)
+		n := 0
+		for n < len(prefix) && n < len(w) && prefix[n] == w[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
+
+// isWordRune reports whether r can be part of an identifier, as the lexer
+// reads them.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,229 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Keys read from a terminal in raw mode.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// editor reads lines from a terminal, completing words on tab and
+// highlighting the line as it is typed.
+type editor struct {
+	in        *bufio.Reader
+	out       io.Writer
+	highlight bool
+	complete  func(line []rune, cursor int) (int, []string)
+
+	fd     int // of the terminal in is read from, put in raw mode while reading
+	prompt string
+	line   []rune
+	cursor int
+}
+
+// newEditor returns an editor for the session s if in and out are
+// terminals, or nil. Highlighting is off if out does not take colours.
+func newEditor(in io.Reader, out io.Writer, s *session) *editor {
+	inFile, ok := in.(*os.File)
+	if !ok || !isTerminal(int(inFile.Fd())) {
+		return nil
+	}
+	if outFile, ok := out.(*os.File); !ok || !isTerminal(int(outFile.Fd())) {
+		return nil
+	}
+	return &editor{
+		in:        bufio.NewReader(in),
+		out:       out,
+		highlight: s.printer.Color,
+		complete:  s.complete,
+		fd:        int(inFile.Fd()),
+	}
+}
+
+// readLine reads a line with the terminal in raw mode. It returns false at
+// the end of the input.
+func (e *editor) readLine() (string, bool) {
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return "", false
+	}
+	defer restore() // nolint
+
+	line, err := e.edit(PROMPT)
+	return line, err == nil
+}
+
+// edit shows prompt and reads keys until enter, returning the line. Ctrl-D
+// on an empty line ends the input with io.EOF.
+func (e *editor) edit(prompt string) (string, error) {
+	e.prompt, e.line, e.cursor = prompt, nil, 0
+	e.redraw()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n") // nolint
+			return string(e.line), nil
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				io.WriteString(e.out, "\r\n") // nolint
+				return "", io.EOF
+			}
+			e.delete(e.cursor, e.cursor+1)
+		case keyCtrlC:
+			// give up the line and start a new one
+			io.WriteString(e.out, "^C\r\n") // nolint
+			e.line, e.cursor = nil, 0
+		case keyBackspace, keyCtrlH:
+			e.delete(e.cursor-1, e.cursor)
+		case keyCtrlA:
+			e.cursor = 0
+		case keyCtrlE:
+			e.cursor = len(e.line)
+		case keyCtrlB:
+			e.move(-1)
+		case keyCtrlF:
+			e.move(1)
+		case keyCtrlK:
+			e.delete(e.cursor, len(e.line))
+		case keyCtrlU:
+			e.delete(0, e.cursor)
+		case keyTab:
+			e.tab()
+		case keyEscape:
+			e.escape()
+		default:
+			if unicode.IsPrint(r) {
+				e.insert(string(r))
+			}
+		}
+		e.redraw()
+	}
+}
+
+// escape handles the escape sequences of the arrow, home, end and delete
+// keys. Others are ignored.
+func (e *editor) escape() {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != '[' && r != 'O' {
+		return
+	}
+	var param []rune
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return
+		}
+		if r < '0' || r > '9' {
+			break
+		}
+		param = append(param, r)
+	}
+	switch {
+	case r == 'C':
+		e.move(1)
+	case r == 'D':
+		e.move(-1)
+	case r == 'H', r == '~' && (string(param) == "1" || string(param) == "7"):
+		e.cursor = 0
+	case r == 'F', r == '~' && (string(param) == "4" || string(param) == "8"):
+		e.cursor = len(e.line)
+	case r == '~' && string(param) == "3":
+		e.delete(e.cursor, e.cursor+1)
+	}
+}
+
+// tab completes the word before the cursor as far as all its completions
+// agree, and lists them if that adds nothing.
+func (e *editor) tab() {
+	start, words := e.complete(e.line, e.cursor)
+	if len(words) == 0 {
+		io.WriteString(e.out, "\a") // nolint
+		return
+	}
+	typed := e.cursor - start
+	if rest := []rune(commonPrefix(words))[typed:]; len(rest) > 0 {
+		e.insert(string(rest))
+		return
+	}
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(words, "  ")) // nolint
+}
+
+func (e *editor) insert(s string) {
+	text := []rune(s)
+	line := make([]rune, 0, len(e.line)+len(text))
+	line = append(line, e.line[:e.cursor]...)
+	line = append(line, text...)
+	e.line = append(line, e.line[e.cursor:]...)
+	e.cursor += len(text)
+}
+
+// delete removes the runes from i up to j, as far as they are in the line.
+func (e *editor) delete(i, j int) {
+	if i < 0 {
+		i = 0
+	}
+	if j > len(e.line) {
+		j = len(e.line)
+	}
+	if i >= j {
+		return
+	}
+	e.line = append(e.line[:i], e.line[j:]...)
+	e.cursor = i
+}
+
+func (e *editor) move(n int) {
+	if c := e.cursor + n; c >= 0 && c <= len(e.line) {
+		e.cursor = c
+	}
+}
+
+// redraw writes the prompt and the line over the current terminal line and
+// puts the cursor back.
+func (e *editor) redraw() {
+	text := string(e.line)
+	if e.highlight {
+		text = highlight(text)
+	}
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, text) // nolint
+	if n := width(e.line[e.cursor:]); n > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", n) // nolint
+	}
+}
+
+// width returns the number of columns runes take up on a terminal, where
+// CJK characters are two columns wide.
+func width(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+			r >= 0xFF01 && r <= 0xFF60 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
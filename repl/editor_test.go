@@ -0,0 +1,112 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	s := newSession(io.Discard)
+	s.eval("let length = 3;") // nolint
+
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"1 + 2\r", "1 + 2"},
+		{"ab\x7fc\r", "ac"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"ac\x1b[Db\x1b[C!\r", "abc!"},
+		{"abc\x1b[H\x1b[3~\x1b[F\x02\x0b\r", "b"},
+		{"abc\x02\x15x\r", "xc"},
+		{"junk\x03ok\r", "ok"},
+		{"pu\t(x)\r", "push(x)"},
+		{"lengt\t\r", "length"},
+		{"le\t\r", "le"},
+		{":ty\t x\r", ":type x"},
+		{"こ\x7fに\r", "に"},
+	}
+
+	for _, tt := range tests {
+		e := &editor{in: bufio.NewReader(strings.NewReader(tt.keys)), out: io.Discard, complete: s.complete}
+		line, err := e.edit(PROMPT)
+		if err != nil {
+			t.Errorf("%q: %v", tt.keys, err)
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf("%q: wrong line. want=%q, got=%q", tt.keys, tt.expected, line)
+		}
+	}
+
+	e := &editor{in: bufio.NewReader(strings.NewReader("\x04")), out: io.Discard, complete: s.complete}
+	if _, err := e.edit(PROMPT); err != io.EOF {
+		t.Errorf("expected io.EOF for ctrl-d on an empty line. got=%v", err)
+	}
+}
+
+func TestEditListsCompletions(t *testing.T) {
+	s := newSession(io.Discard)
+	s.eval("let length = 3;") // nolint
+
+	var out bytes.Buffer
+	e := &editor{in: bufio.NewReader(strings.NewReader("le\t\r")), out: &out, complete: s.complete}
+	e.edit(PROMPT) // nolint
+	if !strings.Contains(out.String(), "\r\nlen  length  let\r\n") {
+		t.Errorf("completions not listed. got=%q", out.String())
+	}
+}
+
+func TestComplete(t *testing.T) {
+	s := newSession(io.Discard)
+	s.eval("let lenient = 1; let f = fn(x) { x };") // nolint
+
+	tests := []struct {
+		line          string
+		expectedStart int
+		expected      []string
+	}{
+		{"len", 0, []string{"len", "lenient"}},
+		{"1 + f", 4, []string{"f", "false", "first", "fn"}},
+		{"ret", 0, []string{"return"}},
+		{"1 + ", 4, nil},
+		{"zzz", 0, nil},
+		{":t", 1, []string{"time", "tokens", "type"}},
+		{"  :", 3, []string{"ast", "env", "help", "load", "reset", "restore", "save", "time", "tokens", "type"}},
+	}
+
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		start, words := s.complete(line, len(line))
+		if start != tt.expectedStart || !reflect.DeepEqual(words, tt.expected) {
+			t.Errorf("%q: wrong completions. want=%d %q, got=%d %q",
+				tt.line, tt.expectedStart, tt.expected, start, words)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{
+			`let s = len("a b");  // note`,
+			magenta + "let" + reset + " s = " + blue + "len" + reset + "(" + green + `"a b"` + reset + ");  " + gray + "// note" + reset,
+		},
+		{"  if (x) { 0x1F } ", "  " + magenta + "if" + reset + " (x) { " + cyan + "0x1F" + reset + " } "},
+		{"null @ true", magenta + "null" + reset + " " + red + "@" + reset + " " + magenta + "true" + reset},
+		{`"open`, green + `"open` + reset},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := highlight(tt.line); got != tt.expected {
+			t.Errorf("%q: wrong highlighting.\nwant=%q\ngot= %q", tt.line, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package repl
+
+import (
+	"monkey-go/evaluator"
+	"monkey-go/lexer"
+	"monkey-go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	magenta = "\x1b[1;35m"
+	green   = "\x1b[32m"
+	cyan    = "\x1b[36m"
+	blue    = "\x1b[34m"
+	red     = "\x1b[31m"
+	gray    = "\x1b[90m"
+	reset   = "\x1b[0m"
+)
+
+// highlight returns line, a line of input, with ANSI colours for its
+// tokens: keywords, literals, builtin names, comments and illegal
+// characters. Everything else, as well as the spacing, is kept as it is.
+func highlight(line string) string {
+	l := lexer.New(line)
+	var toks []token.Token
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		toks = append(toks, tok)
+	}
+	toks = append(toks, l.Comments()...)
+	sort.Slice(toks, func(i, j int) bool { return toks[i].Column < toks[j].Column })
+
+	runes := []rune(line)
+	var out strings.Builder
+	out.WriteString(string(runes[:offset(toks, 0, len(runes))]))
+	for i, tok := range toks {
+		// a token runs to the next one, less the space between them, as the
+		// literal of a string has no quotes
+		text := string(runes[offset(toks, i, len(runes)):offset(toks, i+1, len(runes))])
+		code := strings.TrimRightFunc(text, unicode.IsSpace)
+		if color := colorOf(tok); color != "" {
+			out.WriteString(color + code + reset)
+		} else {
+			out.WriteString(code)
+		}
+		out.WriteString(text[len(code):])
+	}
+	return out.String()
+}
+
+// offset returns the offset of the ith token in the line, or n, the length
+// of the line, after the last one.
+func offset(toks []token.Token, i, n int) int {
+	if i >= len(toks) {
+		return n
+	}
+	return toks[i].Column - 1
+}
+
+func colorOf(tok token.Token) string {
+	switch tok.Type {
+	case token.IDENT:
+		if evaluator.IsBuiltin(tok.Literal) {
+			return blue
+		}
+		return ""
+	case token.INT:
+		return cyan
+	case token.STRING:
+		return green
+	case token.COMMENT:
+		return gray
+	case token.ILLEGAL:
+		return red
+	}
+	if token.LookupIdent(tok.Literal) != token.IDENT {
+		return magenta
+	}
+	return ""
+}
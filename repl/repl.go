@@ -4,50 +4,165 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"monkey-go/ast"
+	"monkey-go/diagnostic"
 	"monkey-go/evaluator"
 	"monkey-go/lexer"
 	"monkey-go/object"
 	"monkey-go/parser"
+	"os"
 	"strings"
 )
 
 const PROMPT = ">> "
 
+// Start reads lines from in and evaluates them, writing the results to
+// out, until in ends or the line exit. Lines starting with a colon are
+// commands, see :help. If in and out are a terminal, lines can be edited,
+// words are completed on tab and the line is highlighted as it is typed.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	StartSession(in, out, "")
+}
+
+// StartSession is Start for a session kept in the file path, unless path
+// is empty: the session is restored from the file if it exists, and saved
+// to it when the REPL ends. A file that cannot be restored is left as it
+// is.
+func StartSession(in io.Reader, out io.Writer, path string) {
+	s := newSession(out)
+	if path != "" {
+		if _, err := os.Stat(path); err != nil || s.restore(path) {
+			defer s.saveCommand(path)
+		} else {
+			fmt.Fprintf(out, "%s could not be restored and will not be saved\n", path) // nolint
+		}
+	}
+	readLine := scanLines(in, out)
+	if e := newEditor(in, out, s); e != nil {
+		readLine = e.readLine
+	}
 
 	for {
-		fmt.Fprintf(out, PROMPT) // nolint
-		scanned := scanner.Scan()
-		if !scanned {
+		line, ok := readLine()
+		if !ok {
 			return
 		}
 
-		line := scanner.Text()
 		// exit the REPL
 		if strings.ToLower(line) == "exit" {
 			break
 		}
 
-		l := lexer.New(line)
-		p := parser.New(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if strings.HasPrefix(strings.TrimSpace(line), ":") {
+			s.command(line)
 			continue
 		}
+		if _, err := s.eval(line); err != nil {
+			return
+		}
+	}
+}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			_, err := io.WriteString(out, evaluated.Inspect()+"\n")
-			if err != nil {
-				return
-			}
+// scanLines returns a function that shows the prompt and reads the next
+// line of in, or returns false at its end.
+func scanLines(in io.Reader, out io.Writer) func() (string, bool) {
+	scanner := bufio.NewScanner(in)
+	return func() (string, bool) {
+		fmt.Fprintf(out, PROMPT) // nolint
+		if !scanner.Scan() {
+			return "", false
 		}
+		return scanner.Text(), true
+	}
+}
+
+// session is the state of a REPL.
+type session struct {
+	out      io.Writer
+	env      *object.Environment
+	macroEnv *object.Environment
+
+	// Errors are shown with the line they were found at. Every line entered,
+	// and every file loaded, is parsed as the next lines of one source, so
+	// that errors in functions defined earlier point there.
+	lines   []string
+	printer *diagnostic.Printer
+
+	// history holds the inputs run since the session began or was reset,
+	// which :save writes: the lines entered and the sources of the files
+	// loaded that parsed. An input that failed is kept too, as the bindings
+	// it made before the error remain.
+	history []string
+}
+
+func newSession(out io.Writer) *session {
+	s := &session{out: out, printer: &diagnostic.Printer{File: "repl", Color: diagnostic.IsTerminal(out)}}
+	s.reset()
+	return s
+}
+
+// reset forgets the bindings, macros, lines and history of the session.
+func (s *session) reset() {
+	s.env = object.NewEnvironment()
+	s.macroEnv = object.NewEnvironment()
+	s.lines = nil
+	s.printer.Source = ""
+	s.history = nil
+}
+
+// eval evaluates line and writes its result. It reports whether line ran
+// without an error; the error is that of writing.
+func (s *session) eval(line string) (bool, error) {
+	program := s.parse(line)
+	if program == nil {
+		return false, nil
+	}
+	s.history = append(s.history, line)
+	evaluated, ok := s.run(program, s.env, s.printer)
+	if !ok || evaluated == nil {
+		return ok, nil
+	}
+	_, err := io.WriteString(s.out, evaluated.Inspect()+"\n")
+	return true, err
+}
+
+// parse parses line as the next line of the session. It writes the errors
+// and returns nil if there are any.
+func (s *session) parse(line string) *ast.Program {
+	p := parser.New(lexer.New(s.addLines(line)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, s.printer, p.ErrorList())
+		return nil
+	}
+	return program
+}
+
+// addLines adds the lines of src to the source of the session and returns
+// src preceded by as many empty lines as the session had, to be parsed.
+func (s *session) addLines(src string) string {
+	offset := len(s.lines)
+	s.lines = append(s.lines, strings.Split(src, "\n")...)
+	s.printer.Source = strings.Join(s.lines, "\n")
+	return strings.Repeat("\n", offset) + src
+}
+
+// run expands the macros of program and evaluates it in env. It writes the
+// errors with printer and returns false for them.
+func (s *session) run(program *ast.Program, env *object.Environment, printer *diagnostic.Printer) (object.Object, bool) {
+	evaluator.DefineMacros(program, s.macroEnv)
+	expanded, err := evaluator.ExpandMacros(program, s.macroEnv)
+	if err != nil {
+		printer.Print(s.out, diagnostic.FromError(err)) // nolint
+		return nil, false
+	}
 
+	evaluated := evaluator.Eval(expanded, env)
+	if err, ok := evaluated.(*object.Error); ok {
+		printer.Print(s.out, diagnostic.FromError(err)) // nolint
+		return nil, false
 	}
+	return evaluated, true
 }
 
 const MONKEY_FACE = `            __,__
@@ -63,11 +178,11 @@ const MONKEY_FACE = `            __,__
            '-----'
 `
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, printer *diagnostic.Printer, errors []*parser.Error) {
 	io.WriteString(out, MONKEY_FACE)                                       // nolint
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n") // nolint
 	io.WriteString(out, " parser errors:\n")                               // nolint
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n") // nolint
+	for _, d := range diagnostic.FromParser(errors) {
+		printer.Print(out, d) // nolint
 	}
 }
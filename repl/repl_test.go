@@ -0,0 +1,250 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// run feeds the lines of input to the REPL and returns what it writes,
+// without the prompts.
+func run(t *testing.T, input string) string {
+	t.Helper()
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+	return strings.ReplaceAll(out.String(), PROMPT, "")
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":tokens let x = \"a\";", `1:1   LET     "let"
+1:5   IDENT   "x"
+1:7   =       "="
+1:9   STRING  "a"
+1:12  ;       ";"
+1:13  EOF     ""
+`},
+		{":ast let f = fn(a, [b]) { a[0] }", `Program
+  LetStatement
+    Name: Identifier f
+    Value: FunctionLiteral
+      Parameters[0]: Identifier a
+      Parameters[1]: ArrayPattern
+        Elements[0]: Identifier b
+      Body: BlockStatement
+        ExpressionStatement
+          Expression: IndexExpression
+            Left: Identifier a
+            Index: IntegerLiteral 0
+`},
+		{"let x = 1;\nconst y = 2;\nlet m = macro(a) { a };\n:env", "1\n2\nlet x = 1\nconst y = 2\nlet m = macro(a){\na\n}\n"},
+		{":type 1\n:type \"s\"\n:type fn() {}", "INTEGER\nSTRING\nFUNCTION\n"},
+		{":type let z = 1; z\nz", "INTEGER\n" + `error: identifier not found: z
+ --> repl:2:1
+  |
+2 | z
+  | ^
+`},
+		{"let x = 1;\n:reset\n:env\nx", `1
+error: identifier not found: x
+ --> repl:1:1
+  |
+1 | x
+  | ^
+`},
+		{":foo", "unknown command :foo, :help lists the commands\n"},
+		{":type", "usage: :type <expr>\n"},
+		{":env x", ":env takes no argument\n"},
+		{"let a = 1;\n  :env  ", "1\nlet a = 1\n"},
+	}
+
+	for _, tt := range tests {
+		if got := run(t, tt.input); got != tt.expected {
+			t.Errorf("%q: wrong output.\nwant=\n%s\ngot=\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	out := run(t, ":help")
+	for _, c := range commands {
+		if !strings.Contains(out, ":"+c.name) {
+			t.Errorf(":help does not list :%s. got=\n%s", c.name, out)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	out := run(t, "let x = 2;\n:time x * 3")
+	if !regexp.MustCompile(`^2\n6\ntook \S+\n$`).MatchString(out) {
+		t.Errorf("wrong output. got=%q", out)
+	}
+
+	out = run(t, ":time 1 + true")
+	if !strings.HasPrefix(out, "error: type mismatch") || strings.Contains(out, "took") {
+		t.Errorf("timing shown for an error. got=%q", out)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.monkey")
+	src := "let double = fn(x) { x * 2 };\nlet y = double(nothing);"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := run(t, "let z = 0;\n:load "+path+"\ndouble(21)\n:load missing.monkey")
+	expected := `0
+error: identifier not found: nothing
+ --> ` + path + `:2:16
+  |
+2 | let y = double(nothing);
+  |                ^^^^^^^
+42
+open missing.monkey: no such file or directory
+`
+	if out != expected {
+		t.Errorf("wrong output.\nwant=\n%s\ngot=\n%s", expected, out)
+	}
+}
+
+func TestLoadedFunctionError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.monkey")
+	if err := os.WriteFile(path, []byte("let g = fn() {\n  missing\n};\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lines of the file follow those entered before
+	out := run(t, "let a = 1;\n:load "+path+"\nlet k = 1;\ng()")
+	expected := `1
+1
+error: identifier not found: missing
+ --> repl:3:3
+  |
+3 |   missing
+  |   ^^^^^^^
+`
+	if out != expected {
+		t.Errorf("wrong output.\nwant=\n%s\ngot=\n%s", expected, out)
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "session.monkey")
+	lib := filepath.Join(dir, "lib.monkey")
+	if err := os.WriteFile(lib, []byte("let inc = fn(x) { x + 1 };\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, "let a = 1 // one\n"+
+		"let f = fn(x) { x + a }\n"+
+		"(2)\n"+
+		"let b = 1 + true;\n"+
+		"let c = ;\n"+
+		":load "+lib+"\n"+
+		"let twice = macro(x) { quote(unquote(x) * 2) };\n"+
+		":save "+path)
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `let a = 1 // one
+;
+let f = fn(x) { x + a }
+;
+(2)
+;
+let b = 1 + true;
+;
+let inc = fn(x) { x + 1 };
+;
+let twice = macro(x) { quote(unquote(x) * 2) };
+;
+`
+	if string(saved) != expected {
+		t.Errorf("wrong session saved.\nwant=\n%s\ngot=\n%s", expected, saved)
+	}
+
+	// the input that failed fails again, at its line in the file, and ends
+	// only itself
+	out := run(t, "let a = 5;\n:restore "+path+"\ntwice(inc(f(1)))\nb")
+	failed := "error: type mismatch: INTEGER + BOOLEAN\n --> " + path + ":7:11\n"
+	if !strings.HasPrefix(out, "5\n"+failed) || !strings.Contains(out, "\n6\nerror: identifier not found: b") {
+		t.Errorf("session not restored. got=\n%s", out)
+	}
+}
+
+func TestRestoredFunctionError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+	if err := os.WriteFile(path, []byte("let a = 1;\n;\nlet g = fn() {\n  missing\n};\n;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the restored inputs are the first lines of the session
+	out := run(t, ":restore "+path+"\ng()")
+	expected := `error: identifier not found: missing
+ --> repl:3:3
+  |
+3 |   missing
+  |   ^^^^^^^
+`
+	if out != expected {
+		t.Errorf("wrong output.\nwant=\n%s\ngot=\n%s", expected, out)
+	}
+}
+
+func TestSaveAfterError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+
+	// a is bound although its input fails
+	out := run(t, "let a = 1; a + true\nlet b = a + 1\n:save "+path+"\n:restore "+path+"\nb")
+	if !strings.HasSuffix(out, "\n2\n") || strings.Contains(out, "identifier not found") {
+		t.Errorf("session not restored. got=\n%s", out)
+	}
+}
+
+func TestRestoreFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+	if err := os.WriteFile(path, []byte("let y = 2;\n;\nlet = 1\n;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the session is kept as it was, without the inputs of the file
+	out := run(t, "let x = 1;\n:restore "+path+"\n:restore missing.monkey\nx\ny")
+	if !strings.Contains(out, "no such file or directory\n1\nerror: identifier not found: y") {
+		t.Errorf("session changed by a failed restore. got=\n%s", out)
+	}
+}
+
+func TestStartSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+
+	var out bytes.Buffer
+	StartSession(strings.NewReader("let x = 2;\nexit\n"), &out, path)
+	out.Reset()
+	StartSession(strings.NewReader("let y = x * 3;"), &out, path)
+	out.Reset()
+	StartSession(strings.NewReader("[x, y]"), &out, path)
+	if got := strings.ReplaceAll(out.String(), PROMPT, ""); got != "[2, 6]\n" {
+		t.Errorf("session not kept. got=%q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("let = 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	StartSession(strings.NewReader("let z = 1;"), &out, path)
+	if saved, _ := os.ReadFile(path); string(saved) != "let = 1" {
+		t.Errorf("session that could not be restored was overwritten. got=%q", saved)
+	}
+	if !strings.Contains(out.String(), path+" could not be restored and will not be saved") {
+		t.Errorf("expected a warning. got=%q", out.String())
+	}
+}
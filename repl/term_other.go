@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package repl
+
+import "errors"
+
+// Lines are read without editing where raw mode is not supported.
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (func() error, error) {
+	return nil, errors.New("raw mode is not supported")
+}
@@ -0,0 +1,211 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"monkey-go/ast"
+	"sort"
+	"strings"
+)
+
+// printTree writes node as an indented tree for :ast, a node per line with
+// the field of its parent it is held in:
+//
+//	Program
+//	  ExpressionStatement
+//	    Expression: InfixExpression +
+//	      Left: IntegerLiteral 1
+//	      Right: IntegerLiteral 2
+func printTree(out io.Writer, node ast.Node) {
+	t := &tree{out: out}
+	t.node("", node)
+}
+
+type tree struct {
+	out   io.Writer
+	depth int
+}
+
+func (t *tree) line(label, text string) {
+	if label != "" {
+		text = label + ": " + text
+	}
+	fmt.Fprintf(t.out, "%s%s\n", strings.Repeat("  ", t.depth), text) // nolint
+}
+
+// node writes node and its children. Nil fields, such as a missing else,
+// are left out.
+func (t *tree) node(label string, node ast.Node) {
+	switch node := node.(type) {
+	case nil:
+		return
+	case *ast.Program:
+		t.line(label, "Program")
+		t.statements(node.Statements)
+	case *ast.BlockStatement:
+		if node == nil {
+			return
+		}
+		t.line(label, "BlockStatement")
+		t.statements(node.Statements)
+	case *ast.LetStatement:
+		text := "LetStatement"
+		if node.IsConst() {
+			text += " const"
+		}
+		t.line(label, text)
+		t.children(func() {
+			t.node("Name", node.Name)
+			t.node("Pattern", node.Pattern)
+			t.node("Value", node.Value)
+		})
+	case *ast.ReturnStatement:
+		t.line(label, "ReturnStatement")
+		t.children(func() { t.node("ReturnValue", node.ReturnValue) })
+	case *ast.ExpressionStatement:
+		t.line(label, "ExpressionStatement")
+		t.children(func() { t.node("Expression", node.Expression) })
+	case *ast.Identifier:
+		if node == nil {
+			return
+		}
+		t.line(label, "Identifier "+node.Value)
+	case *ast.IntegerLiteral:
+		t.line(label, fmt.Sprintf("IntegerLiteral %d", node.Value))
+	case *ast.StringLiteral:
+		t.line(label, fmt.Sprintf("StringLiteral %q", node.Value))
+	case *ast.Boolean:
+		t.line(label, fmt.Sprintf("Boolean %t", node.Value))
+	case *ast.NullLiteral:
+		t.line(label, "NullLiteral")
+	case *ast.PrefixExpression:
+		t.line(label, "PrefixExpression "+node.Operator)
+		t.children(func() { t.node("Right", node.Right) })
+	case *ast.InfixExpression:
+		t.line(label, "InfixExpression "+node.Operator)
+		t.children(func() {
+			t.node("Left", node.Left)
+			t.node("Right", node.Right)
+		})
+	case *ast.IfExpression:
+		if node == nil {
+			return
+		}
+		t.line(label, "IfExpression")
+		t.children(func() {
+			t.node("Condition", node.Condition)
+			t.node("Consequence", node.Consequence)
+			t.node("Alternative", node.Alternative)
+			t.node("ElseIf", node.ElseIf)
+		})
+	case *ast.ConditionalExpression:
+		t.line(label, "ConditionalExpression")
+		t.children(func() {
+			t.node("Condition", node.Condition)
+			t.node("Consequence", node.Consequence)
+			t.node("Alternative", node.Alternative)
+		})
+	case *ast.FunctionLiteral:
+		t.line(label, "FunctionLiteral")
+		t.children(func() {
+			t.expressions("Parameters", node.Parameters)
+			t.node("Body", node.Body)
+		})
+	case *ast.MacroLiteral:
+		t.line(label, "MacroLiteral")
+		t.children(func() {
+			t.expressions("Parameters", node.Parameters)
+			t.node("Body", node.Body)
+		})
+	case *ast.CallExpression:
+		t.line(label, "CallExpression")
+		t.children(func() {
+			t.node("Function", node.Function)
+			t.expressions("Arguments", node.Arguments)
+		})
+	case *ast.ArrayLiteral:
+		t.line(label, "ArrayLiteral")
+		t.children(func() { t.expressions("Elements", node.Elements) })
+	case *ast.IndexExpression:
+		t.line(label, "IndexExpression")
+		t.children(func() {
+			t.node("Left", node.Left)
+			t.node("Index", node.Index)
+		})
+	case *ast.HashMapLiteral:
+		t.line(label, "HashMapLiteral")
+		t.children(func() {
+			for _, key := range sortedKeys(node.Pairs) {
+				t.node("Key", key)
+				t.node("Value", node.Pairs[key])
+			}
+		})
+	case *ast.MatchExpression:
+		t.line(label, "MatchExpression")
+		t.children(func() {
+			t.node("Subject", node.Subject)
+			for _, arm := range node.Arms {
+				t.line("Arm", "MatchArm")
+				t.children(func() {
+					t.node("Pattern", arm.Pattern)
+					t.node("Guard", arm.Guard)
+					t.node("Body", arm.Body)
+				})
+			}
+		})
+	case *ast.ArrayPattern:
+		t.line(label, "ArrayPattern")
+		t.children(func() {
+			t.expressions("Elements", node.Elements)
+			t.node("Rest", node.Rest)
+		})
+	case *ast.HashPattern:
+		t.line(label, "HashPattern")
+		t.children(func() {
+			for _, pair := range node.Pairs {
+				t.node("Key", pair.Key)
+				t.node("Value", pair.Value)
+			}
+		})
+	default:
+		t.line(label, fmt.Sprintf("%T %s", node, node))
+	}
+}
+
+func (t *tree) children(f func()) {
+	t.depth++
+	f()
+	t.depth--
+}
+
+func (t *tree) statements(stmts []ast.Statement) {
+	t.children(func() {
+		for _, stmt := range stmts {
+			t.node("", stmt)
+		}
+	})
+}
+
+// expressions writes the elements of a list field, labelled with its name
+// and their index.
+func (t *tree) expressions(label string, exprs []ast.Expression) {
+	for i, e := range exprs {
+		t.node(fmt.Sprintf("%s[%d]", label, i), e)
+	}
+}
+
+// sortedKeys returns the keys of a hash literal in source order.
+func sortedKeys(pairs map[ast.Expression]ast.Expression) []ast.Expression {
+	keys := make([]ast.Expression, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := ast.Start(keys[i]), ast.Start(keys[j])
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return keys
+}
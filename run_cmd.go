@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey-go/cover"
+	"monkey-go/diagnostic"
+	"monkey-go/evaluator"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"monkey-go/profile"
+	"os"
+	"strings"
+)
+
+// runRun implements `monkey run [-profile file | -cover file] script`,
+// which runs a script. Errors are printed with the source line they were
+// found at, and the exit status is 1 if there is one.
+// With -profile, a table of the time spent in each function is printed to
+// standard error and a pprof profile is written to file. With -cover, a
+// coverage summary is printed to standard error and a report is written to
+// file: an HTML page if its name ends in .html, an LCOV tracefile
+// otherwise.
+func runRun(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	profilePath := flags.String("profile", "", "write a pprof profile of the Monkey functions to `file`")
+	coverPath := flags.String("cover", "", "write a coverage report to `file`")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey run [-profile file | -cover file] script\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if *profilePath != "" && *coverPath != "" {
+		fmt.Fprintln(os.Stderr, "monkey run: cannot use -profile and -cover together")
+		return 2
+	}
+	path := flags.Arg(0)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	printer := &diagnostic.Printer{File: path, Source: string(src), Color: diagnostic.IsTerminal(os.Stderr)}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, d := range diagnostic.FromParser(p.ErrorList()) {
+			printer.Print(os.Stderr, d)
+		}
+		return 1
+	}
+
+	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, errObj := evaluator.ExpandMacros(program, macroEnv)
+	if errObj != nil {
+		printer.Print(os.Stderr, diagnostic.FromError(errObj))
+		return 1
+	}
+
+	var result object.Object
+	switch {
+	case *profilePath != "":
+		prof := profile.New(path, expanded)
+		result = prof.Run(expanded, env)
+		if err := writeProfile(prof, *profilePath); err != nil {
+			fmt.Fprintf(os.Stderr, "monkey run: %v\n", err)
+			return 1
+		}
+	case *coverPath != "":
+		c := cover.New(path, src, expanded)
+		result = c.Run(expanded, env)
+		if err := writeCoverage(c.Profile(), *coverPath); err != nil {
+			fmt.Fprintf(os.Stderr, "monkey run: %v\n", err)
+			return 1
+		}
+	default:
+		result = evaluator.Eval(expanded, env)
+	}
+
+	if errObj, ok := result.(*object.Error); ok {
+		printer.Print(os.Stderr, diagnostic.FromError(errObj))
+		return 1
+	}
+	return 0
+}
+
+func writeProfile(prof *profile.Profiler, path string) error {
+	if err := prof.WriteTable(os.Stderr); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := prof.WritePprof(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeCoverage(p *cover.Profile, path string) error {
+	fmt.Fprintf(os.Stderr, "coverage: %s\n", p.Summary())
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := cover.WriteLCOV
+	if strings.HasSuffix(path, ".html") {
+		write = cover.WriteHTML
+	}
+	if err := write(f, []*cover.Profile{p}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey-go/diagnostic"
+	"monkey-go/testrunner"
+	"os"
+)
+
+// runTest implements `monkey test [-v] [paths...]`, which runs the tests in
+// the *_test.monkey files under paths, the current directory by default.
+// The exit status is 1 if a test fails or a file cannot be run.
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "also list the tests that pass")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey test [-v] [paths...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := testrunner.Find(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status := 0
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		results, err := testrunner.Run(string(src))
+		if err != nil {
+			printSetupError(path, string(src), err.(*testrunner.SetupError))
+			fmt.Printf("FAIL\t%s\t[setup failed]\n", path)
+			status = 1
+			continue
+		}
+		if testrunner.Report(os.Stdout, path, results, *verbose) > 0 {
+			status = 1
+		}
+	}
+	return status
+}
+
+func printSetupError(path, src string, err *testrunner.SetupError) {
+	printer := &diagnostic.Printer{File: path, Source: src, Color: diagnostic.IsTerminal(os.Stderr)}
+	if err.Macro != nil {
+		printer.Print(os.Stderr, diagnostic.FromError(err.Macro))
+		return
+	}
+	for _, d := range diagnostic.FromParser(err.Syntax) {
+		printer.Print(os.Stderr, d)
+	}
+}
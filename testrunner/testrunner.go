@@ -0,0 +1,192 @@
+// Package testrunner runs tests written in Monkey. Tests live in files
+// named *_test.monkey, and a test is a function bound by a top-level let or
+// const statement to a name starting with "test_". It is called without
+// arguments:
+//
+//	let test_sum = fn() {
+//	    assertEq(1 + 2, 3);
+//	};
+//
+// A test fails if it returns an error, usually one of the assert, assertEq
+// and assertError builtins. It is used by `monkey test`.
+package testrunner
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"monkey-go/ast"
+	"monkey-go/evaluator"
+	"monkey-go/lexer"
+	"monkey-go/object"
+	"monkey-go/parser"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Suffix ends the names of the files tests are found in.
+const Suffix = "_test.monkey"
+
+// Test is a test function.
+type Test struct {
+	Name string
+	Line int
+}
+
+// Result is the outcome of a test. Err is nil if the test passed.
+type Result struct {
+	Test
+	Err *object.Error
+}
+
+// Find returns the test files under paths: the files named, and the files
+// whose names end in Suffix in the directories named and their
+// subdirectories, sorted.
+func Find(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), Suffix) {
+				found = append(found, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// Tests returns the tests of program in source order.
+func Tests(program *ast.Program) []Test {
+	var tests []Test
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let == nil || let.Name == nil || !strings.HasPrefix(let.Name.Value, "test_") {
+			continue
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			tests = append(tests, Test{Name: let.Name.Value, Line: let.Token.Line})
+		}
+	}
+	return tests
+}
+
+// SetupError is an error that keeps the tests of a file from running: the
+// file does not parse or its macros cannot be expanded.
+type SetupError struct {
+	Syntax []*parser.Error // the syntax errors, if any
+	Macro  *object.Error   // the error of the macro expansion otherwise
+}
+
+func (e *SetupError) Error() string {
+	if e.Macro != nil {
+		return e.Macro.Inspect()
+	}
+	var msgs []string
+	for _, err := range e.Syntax {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Run runs the tests of the source file src. Each test runs in a fresh
+// environment, in which the whole file is evaluated first. The error is a
+// *SetupError.
+func Run(src string) ([]Result, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &SetupError{Syntax: p.ErrorList()}
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, errObj := evaluator.ExpandMacros(program, macroEnv)
+	if errObj != nil {
+		return nil, &SetupError{Macro: errObj}
+	}
+
+	var results []Result
+	for _, test := range Tests(program) {
+		results = append(results, Result{Test: test, Err: run(expanded, test.Name)})
+	}
+	return results, nil
+}
+
+func run(program ast.Node, name string) *object.Error {
+	env := object.NewEnvironment()
+	if errObj, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		return errObj
+	}
+	fn, ok := env.Get(name)
+	if !ok {
+		return &object.Error{Message: fmt.Sprintf("%s is not defined", name)}
+	}
+	if errObj, ok := evaluator.Call(fn).(*object.Error); ok {
+		return errObj
+	}
+	return nil
+}
+
+// Report writes the results of the tests of file to w in the style of
+// `go test`: each failed test with its error and where it was raised, each
+// passed test too if verbose, and a summary line. It returns the number of tests that failed.
+func Report(w io.Writer, file string, results []Result, verbose bool) int {
+	failed := 0
+	for _, r := range results {
+		if r.Err == nil {
+			if verbose {
+				fmt.Fprintf(w, "--- PASS: %s (%s:%d)\n", r.Name, file, r.Line)
+			}
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "--- FAIL: %s (%s:%d)\n", r.Name, file, r.Line)
+		msg := r.Err.Message
+		if r.Err.Line != 0 {
+			msg = fmt.Sprintf("%s:%d:%d: %s", file, r.Err.Line, r.Err.Column, msg)
+		}
+		if r.Err.Hint != "" {
+			msg += "\nhelp: " + r.Err.Hint
+		}
+		for _, line := range strings.Split(msg, "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+
+	switch {
+	case len(results) == 0:
+		fmt.Fprintf(w, "?   \t%s\t[no tests]\n", file)
+	case failed > 0:
+		fmt.Fprintf(w, "FAIL\t%s\t%d of %s failed\n", file, failed, countTests(len(results)))
+	default:
+		fmt.Fprintf(w, "ok  \t%s\t%s\n", file, countTests(len(results)))
+	}
+	return failed
+}
+
+func countTests(n int) string {
+	if n == 1 {
+		return "1 test"
+	}
+	return fmt.Sprintf("%d tests", n)
+}
@@ -0,0 +1,106 @@
+package testrunner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const input = `let counter = [];
+let add = fn(a, b) { a + b };
+
+let test_add = fn() {
+    assertEq(add(1, 2), 3);
+};
+let helper = fn() { assert(false) };
+let test_wrong = fn() {
+    assertEq([add(1, 1), 3], [2, 4], "sums");
+};
+let test_fresh = fn() {
+    // counter is evaluated again for each test
+    assertEq(push(counter, 1), [1]);
+};
+const test_error = fn() { assertError(fn() { add(1) }, "wrong number") };
+let test_args = fn(x) { x };`
+
+func TestRun(t *testing.T) {
+	results, err := Run(input)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var out bytes.Buffer
+	if failed := Report(&out, "add_test.monkey", results, true); failed != 2 {
+		t.Errorf("wrong number of failed tests. want=2, got=%d", failed)
+	}
+	expected := `--- PASS: test_add (add_test.monkey:4)
+--- FAIL: test_wrong (add_test.monkey:8)
+    add_test.monkey:9:5: assertEq failed: sums
+      got:  [2, 3]
+      want: [2, 4]
+      at [1]: got 3, want 4
+--- PASS: test_fresh (add_test.monkey:11)
+--- PASS: test_error (add_test.monkey:15)
+--- FAIL: test_args (add_test.monkey:16)
+    wrong number of arguments. got=0, want=1
+FAIL	add_test.monkey	2 of 5 tests failed
+`
+	if out.String() != expected {
+		t.Errorf("wrong report.\nwant=\n%s\ngot=\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	Report(&out, "add_test.monkey", results[:1], false)
+	if out.String() != "ok  \tadd_test.monkey\t1 test\n" {
+		t.Errorf("wrong report. got=%q", out.String())
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	_, err := Run("let = 1;")
+	if setup, ok := err.(*SetupError); !ok || len(setup.Syntax) != 1 {
+		t.Errorf("expected a syntax error. got=%v", err)
+	}
+
+	results, err := Run(`let test_a = fn() { 1 }; 1 + true`)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(results) != 1 || results[0].Err == nil ||
+		results[0].Err.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("expected the error of the file. got=%+v", results)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b_test.monkey", "a.monkey", "sub/c_test.monkey", "sub/a_test.monkey"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := Find([]string{filepath.Join(dir, "a.monkey"), dir})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	expected := []string{
+		filepath.Join(dir, "a.monkey"),
+		filepath.Join(dir, "b_test.monkey"),
+		filepath.Join(dir, "sub/a_test.monkey"),
+		filepath.Join(dir, "sub/c_test.monkey"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("wrong files.\nwant=%q\ngot=%q", expected, files)
+	}
+
+	if _, err := Find([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Errorf("expected an error for a missing path")
+	}
+}
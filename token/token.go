@@ -1,10 +1,14 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // 1-based line of the first character, 0 if unknown
+	Column  int // 1-based column of the first character, counted in runes
 }
 
 const (
@@ -16,6 +20,8 @@ const (
 	INT    = "INT"    // 1234567890
 	STRING = "STRING" // Unicode
 
+	COMMENT = "COMMENT" // // から行末まで
+
 	// 演算子
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -23,6 +29,14 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	POWER    = "**"
+
+	AMPERSAND = "&"
+	PIPE      = "|"
+	CARET     = "^"
+	TILDE     = "~"
+	LSHIFT    = "<<"
+	RSHIFT    = ">>"
 
 	LT = "<"
 	GT = ">"
@@ -30,10 +44,14 @@ const (
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	ARROW    = "=>"
+	QUESTION = "?"
+
 	// デリミタ
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	ELLIPSIS  = "..."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -45,21 +63,39 @@ const (
 	// キーワード
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
+	NULL     = "NULL"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MATCH    = "MATCH"
+	MACRO    = "MACRO"
 )
 
 var keyword = map[string]TokenType{
 	"fn":     FUNCTION,
 	"let":    LET,
+	"const":  CONST,
 	"true":   TRUE,
 	"false":  FALSE,
+	"null":   NULL,
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"match":  MATCH,
+	"macro":  MACRO,
+}
+
+// Keywords returns the reserved words, sorted.
+func Keywords() []string {
+	words := make([]string, 0, len(keyword))
+	for word := range keyword {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
 }
 
 func LookupIdent(ident string) TokenType {
@@ -12,19 +12,30 @@ func TestTokenTypeUniqueness(t *testing.T) {
 		{"IDENT", IDENT},
 		{"INT", INT},
 		{"STRING", STRING},
+		{"COMMENT", COMMENT},
 		{"ASSIGN", ASSIGN},
 		{"PLUS", PLUS},
 		{"MINUS", MINUS},
 		{"BANG", BANG},
 		{"ASTERISK", ASTERISK},
 		{"SLASH", SLASH},
+		{"POWER", POWER},
+		{"AMPERSAND", AMPERSAND},
+		{"PIPE", PIPE},
+		{"CARET", CARET},
+		{"TILDE", TILDE},
+		{"LSHIFT", LSHIFT},
+		{"RSHIFT", RSHIFT},
 		{"LT", LT},
 		{"GT", GT},
 		{"EQ", EQ},
 		{"NOT_EQ", NOT_EQ},
+		{"ARROW", ARROW},
+		{"QUESTION", QUESTION},
 		{"COMMA", COMMA},
 		{"SEMICOLON", SEMICOLON},
 		{"COLON", COLON},
+		{"ELLIPSIS", ELLIPSIS},
 		{"LPAREN", LPAREN},
 		{"RPAREN", RPAREN},
 		{"LBRACE", LBRACE},
@@ -33,11 +44,14 @@ func TestTokenTypeUniqueness(t *testing.T) {
 		{"RBRACKET", RBRACKET},
 		{"FUNCTION", FUNCTION},
 		{"LET", LET},
+		{"CONST", CONST},
 		{"TRUE", TRUE},
 		{"FALSE", FALSE},
 		{"IF", IF},
 		{"ELSE", ELSE},
 		{"RETURN", RETURN},
+		{"MATCH", MATCH},
+		{"MACRO", MACRO},
 	}
 
 	seen := make(map[TokenType]string)